@@ -3,8 +3,14 @@ module github.com/dvonthenen/rabbitmq-manager
 go 1.18
 
 require (
+	github.com/klauspost/compress v1.15.15
 	github.com/rabbitmq/amqp091-go v1.5.0
+	github.com/vmihailenco/msgpack/v5 v5.3.5
+	google.golang.org/protobuf v1.28.1
 	k8s.io/klog/v2 v2.80.1
 )
 
-require github.com/go-logr/logr v1.2.0 // indirect
+require (
+	github.com/go-logr/logr v1.2.0 // indirect
+	github.com/vmihailenco/tagparser/v2 v2.0.0 // indirect
+)
@@ -0,0 +1,139 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package circuitbreaker
+
+import (
+	"time"
+
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+func New(threshold int, openInterval time.Duration, probes int, onChange StateFunc) (*Breaker, error) {
+	if threshold < 0 || openInterval < 0 || probes < 0 {
+		return nil, ErrInvalidBreaker
+	}
+	if openInterval == 0 {
+		openInterval = DefaultOpenInterval
+	}
+	if probes == 0 {
+		probes = DefaultProbes
+	}
+
+	b := &Breaker{
+		threshold:    threshold,
+		openInterval: openInterval,
+		probes:       probes,
+		onChange:     onChange,
+		state:        interfaces.CircuitStateClosed,
+	}
+	return b, nil
+}
+
+func (b *Breaker) State() interfaces.CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.state
+}
+
+/*
+	Reports whether work may go ahead, which is any time the breaker is not open
+*/
+func (b *Breaker) Allow() bool {
+	return b.State() != interfaces.CircuitStateOpen
+}
+
+/*
+	Records the result of a unit of work
+*/
+func (b *Breaker) Record(err error) {
+	b.mu.Lock()
+
+	from := b.state
+	switch {
+	case b.state == interfaces.CircuitStateOpen:
+		// late results from work started before the breaker opened
+	case err != nil && b.state == interfaces.CircuitStateHalfOpen:
+		b.open()
+	case err != nil:
+		b.failures++
+		if b.failures >= b.threshold {
+			b.open()
+		}
+	case b.state == interfaces.CircuitStateHalfOpen:
+		b.successes++
+		if b.successes >= b.probes {
+			b.state = interfaces.CircuitStateClosed
+			b.failures = 0
+		}
+	default:
+		b.failures = 0
+	}
+	to := b.state
+
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+/*
+	Caller must hold mu
+*/
+func (b *Breaker) open() {
+	b.state = interfaces.CircuitStateOpen
+	b.failures = 0
+	b.successes = 0
+
+	b.timer = time.AfterFunc(b.openInterval, b.halfOpen)
+}
+
+func (b *Breaker) halfOpen() {
+	b.mu.Lock()
+
+	from := b.state
+	if b.state == interfaces.CircuitStateOpen {
+		b.state = interfaces.CircuitStateHalfOpen
+		b.successes = 0
+	}
+	to := b.state
+
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+/*
+	Closes the breaker and forgets the failures seen so far
+*/
+func (b *Breaker) Reset() {
+	b.mu.Lock()
+
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+
+	from := b.state
+	b.state = interfaces.CircuitStateClosed
+	b.failures = 0
+	b.successes = 0
+	to := b.state
+
+	b.mu.Unlock()
+
+	b.notify(from, to)
+}
+
+func (b *Breaker) notify(from, to interfaces.CircuitState) {
+	if from == to {
+		return
+	}
+
+	klog.V(3).Infof("Circuit breaker %d -> %d\n", from, to)
+	if b.onChange != nil {
+		b.onChange(from, to)
+	}
+}
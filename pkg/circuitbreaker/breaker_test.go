@@ -0,0 +1,240 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package circuitbreaker
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+const (
+	closed   = interfaces.CircuitStateClosed
+	open     = interfaces.CircuitStateOpen
+	halfOpen = interfaces.CircuitStateHalfOpen
+)
+
+var errWork = errors.New("work failed")
+
+type transition struct {
+	from interfaces.CircuitState
+	to   interfaces.CircuitState
+}
+
+type recorder struct {
+	transitions []transition
+	mu          sync.Mutex
+}
+
+func (r *recorder) onChange(from, to interfaces.CircuitState) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.transitions = append(r.transitions, transition{from, to})
+}
+
+func (r *recorder) get() []transition {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]transition{}, r.transitions...)
+}
+
+func TestNewValidation(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold int
+		interval  time.Duration
+		probes    int
+		err       error
+	}{
+		{"defaults", 1, 0, 0, nil},
+		{"negative threshold", -1, 0, 0, ErrInvalidBreaker},
+		{"negative interval", 1, -time.Second, 0, ErrInvalidBreaker},
+		{"negative probes", 1, 0, -1, ErrInvalidBreaker},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := New(tt.threshold, tt.interval, tt.probes, nil)
+			if err != tt.err {
+				t.Fatalf("New() err = %v, want %v", err, tt.err)
+			}
+			if err == nil && (b.openInterval != DefaultOpenInterval || b.probes != DefaultProbes) {
+				t.Fatalf("defaults not applied: %v %d", b.openInterval, b.probes)
+			}
+		})
+	}
+}
+
+/*
+	Steps are results recorded with Record, "half" fires the open interval and
+	"reset" calls Reset
+*/
+func TestTransitions(t *testing.T) {
+	tests := []struct {
+		name        string
+		threshold   int
+		probes      int
+		steps       []interface{}
+		state       interfaces.CircuitState
+		transitions []transition
+	}{
+		{
+			name:      "successes keep it closed",
+			threshold: 2,
+			steps:     []interface{}{nil, nil, nil},
+			state:     closed,
+		},
+		{
+			name:        "opens at the threshold",
+			threshold:   3,
+			steps:       []interface{}{errWork, errWork, errWork},
+			state:       open,
+			transitions: []transition{{closed, open}},
+		},
+		{
+			name:      "a success resets the failure count",
+			threshold: 2,
+			steps:     []interface{}{errWork, nil, errWork, nil},
+			state:     closed,
+		},
+		{
+			name:        "results while open are ignored",
+			threshold:   1,
+			steps:       []interface{}{errWork, nil, nil, errWork},
+			state:       open,
+			transitions: []transition{{closed, open}},
+		},
+		{
+			name:        "half-open after the interval",
+			threshold:   1,
+			steps:       []interface{}{errWork, "half"},
+			state:       halfOpen,
+			transitions: []transition{{closed, open}, {open, halfOpen}},
+		},
+		{
+			name:        "closes after enough probes",
+			threshold:   1,
+			probes:      2,
+			steps:       []interface{}{errWork, "half", nil, nil},
+			state:       closed,
+			transitions: []transition{{closed, open}, {open, halfOpen}, {halfOpen, closed}},
+		},
+		{
+			name:        "stays half-open until the probes succeed",
+			threshold:   1,
+			probes:      2,
+			steps:       []interface{}{errWork, "half", nil},
+			state:       halfOpen,
+			transitions: []transition{{closed, open}, {open, halfOpen}},
+		},
+		{
+			name:        "a failed probe opens again",
+			threshold:   3,
+			probes:      2,
+			steps:       []interface{}{errWork, errWork, errWork, "half", nil, errWork},
+			state:       open,
+			transitions: []transition{{closed, open}, {open, halfOpen}, {halfOpen, open}},
+		},
+		{
+			name:        "failures start over after closing",
+			threshold:   2,
+			steps:       []interface{}{errWork, errWork, "half", nil, errWork},
+			state:       closed,
+			transitions: []transition{{closed, open}, {open, halfOpen}, {halfOpen, closed}},
+		},
+		{
+			name:        "reset closes",
+			threshold:   1,
+			steps:       []interface{}{errWork, "reset"},
+			state:       closed,
+			transitions: []transition{{closed, open}, {open, closed}},
+		},
+		{
+			name:      "reset while closed is silent",
+			threshold: 1,
+			steps:     []interface{}{"reset"},
+			state:     closed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{}
+			b, err := New(tt.threshold, time.Hour, tt.probes, r.onChange)
+			if err != nil {
+				t.Fatalf("New failed. Err: %v", err)
+			}
+			defer b.Reset()
+
+			for _, step := range tt.steps {
+				switch step {
+				case "half":
+					b.halfOpen()
+				case "reset":
+					b.Reset()
+				case nil:
+					b.Record(nil)
+				default:
+					b.Record(step.(error))
+				}
+			}
+
+			if b.State() != tt.state {
+				t.Fatalf("State() = %d, want %d", b.State(), tt.state)
+			}
+			if b.Allow() != (tt.state != open) {
+				t.Fatalf("Allow() = %v in state %d", b.Allow(), tt.state)
+			}
+			got := r.get()
+			if len(got) != len(tt.transitions) {
+				t.Fatalf("transitions = %v, want %v", got, tt.transitions)
+			}
+			for i := range got {
+				if got[i] != tt.transitions[i] {
+					t.Fatalf("transitions = %v, want %v", got, tt.transitions)
+				}
+			}
+		})
+	}
+}
+
+func TestOpenIntervalTimer(t *testing.T) {
+	b, err := New(1, 20*time.Millisecond, 1, nil)
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+
+	b.Record(errWork)
+	if b.State() != open {
+		t.Fatalf("State() = %d, want open", b.State())
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for b.State() != halfOpen {
+		if time.Now().After(deadline) {
+			t.Fatalf("breaker never moved to half-open")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestResetStopsTimer(t *testing.T) {
+	b, err := New(1, 20*time.Millisecond, 1, nil)
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+
+	b.Record(errWork)
+	b.Reset()
+	time.Sleep(50 * time.Millisecond)
+
+	if b.State() != closed {
+		t.Fatalf("State() = %d after Reset, want closed", b.State())
+	}
+}
@@ -0,0 +1,22 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package circuitbreaker
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultOpenInterval how long the breaker stays open before probing
+	DefaultOpenInterval time.Duration = 30 * time.Second
+
+	// DefaultProbes successes needed in half-open before the breaker closes
+	DefaultProbes int = 1
+)
+
+var (
+	// ErrInvalidBreaker the threshold, open interval and probes cannot be negative
+	ErrInvalidBreaker = errors.New("the threshold, open interval and probes cannot be negative")
+)
@@ -0,0 +1,34 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package circuitbreaker
+
+import (
+	"sync"
+	"time"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Called after every state transition, outside of the breaker lock
+*/
+type StateFunc func(from, to interfaces.CircuitState)
+
+/*
+	Opens after threshold consecutive failures, moves to half-open once the open
+	interval has passed and closes again after enough successful probes. Any
+	failure while half-open opens it again.
+*/
+type Breaker struct {
+	threshold    int
+	openInterval time.Duration
+	probes       int
+	onChange     StateFunc
+
+	state     interfaces.CircuitState
+	failures  int
+	successes int
+	timer     *time.Timer
+	mu        sync.Mutex
+}
@@ -0,0 +1,30 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package claimcheck
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+/*
+	Hex SHA-256 of a body, sent with the claim so the fetched body can be matched
+*/
+func Digest(body []byte) string {
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:])
+}
+
+/*
+	Checks a fetched body against the size and digest it was claimed with
+*/
+func Check(body []byte, size int64, digest string) error {
+	if int64(len(body)) != size {
+		return ErrSizeMismatch
+	}
+	if Digest(body) != digest {
+		return ErrDigestMismatch
+	}
+	return nil
+}
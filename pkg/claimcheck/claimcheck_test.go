@@ -0,0 +1,220 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package claimcheck
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newStore(t *testing.T, ttl time.Duration) (*FileStore, string) {
+	t.Helper()
+
+	dir := filepath.Join(t.TempDir(), "blobs")
+	store, err := NewFileStore(dir, ttl)
+	if err != nil {
+		t.Fatalf("NewFileStore failed. Err: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store, dir
+}
+
+func TestStoreFetch(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		body []byte
+	}{
+		{"plain key", "3f2b0c1e-7c2d-4c55-9d57-1b2d3e4f5a6b", []byte("body")},
+		{"empty body", "empty", []byte{}},
+		{"large body", "large", bytes.Repeat([]byte{0xab}, 1<<20)},
+		{"key with spaces", "a key", []byte("x")},
+	}
+
+	store, _ := newStore(t, 0)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := store.Put(tt.key, tt.body)
+			if err != nil {
+				t.Fatalf("Put failed. Err: %v", err)
+			}
+
+			got, err := store.Get(tt.key)
+			if err != nil {
+				t.Fatalf("Get failed. Err: %v", err)
+			}
+			if !bytes.Equal(got, tt.body) {
+				t.Fatalf("Get returned %d bytes, want %d", len(got), len(tt.body))
+			}
+
+			err = store.Delete(tt.key)
+			if err != nil {
+				t.Fatalf("Delete failed. Err: %v", err)
+			}
+			if _, err := store.Get(tt.key); err != ErrBlobNotFound {
+				t.Fatalf("Get after Delete err = %v, want %v", err, ErrBlobNotFound)
+			}
+		})
+	}
+}
+
+func TestKeysStayInsideDirectory(t *testing.T) {
+	keys := []string{
+		"../escape",
+		"../../escape",
+		"/absolute",
+		"..",
+		"a/../../b",
+		`..\escape`,
+	}
+
+	store, dir := newStore(t, 0)
+	parent := filepath.Dir(dir)
+
+	for _, key := range keys {
+		t.Run(key, func(t *testing.T) {
+			err := store.Put(key, []byte(key))
+			if err != nil {
+				t.Fatalf("Put failed. Err: %v", err)
+			}
+			got, err := store.Get(key)
+			if err != nil || string(got) != key {
+				t.Fatalf("Get returned %q. Err: %v", got, err)
+			}
+		})
+	}
+
+	// every body landed in the store directory and nothing next to it
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != filepath.Base(dir) {
+		t.Fatalf("files written outside the store: %v", entries)
+	}
+	entries, err = os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(keys) {
+		t.Fatalf("store holds %d files, want %d", len(entries), len(keys))
+	}
+}
+
+func TestInvalidKeys(t *testing.T) {
+	store, _ := newStore(t, 0)
+
+	if err := store.Put("", nil); err != ErrInvalidBlobKey {
+		t.Fatalf("Put() err = %v, want %v", err, ErrInvalidBlobKey)
+	}
+	if _, err := store.Get(""); err != ErrInvalidBlobKey {
+		t.Fatalf("Get() err = %v, want %v", err, ErrInvalidBlobKey)
+	}
+	if err := store.Delete(""); err != ErrInvalidBlobKey {
+		t.Fatalf("Delete() err = %v, want %v", err, ErrInvalidBlobKey)
+	}
+	if err := store.Delete("missing"); err != nil {
+		t.Fatalf("Delete of a missing body err = %v", err)
+	}
+}
+
+func TestSweep(t *testing.T) {
+	store, dir := newStore(t, time.Hour)
+
+	for _, key := range []string{"old", "new"} {
+		if err := store.Put(key, []byte(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// a partial write left behind by a crash
+	partial := filepath.Join(dir, "partial"+fileExtension+tmpExtension)
+	if err := os.WriteFile(partial, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	// a file the store does not own
+	foreign := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(foreign, []byte("x"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	old := time.Now().Add(-2 * time.Hour)
+	for _, filename := range []string{store.filename("old"), partial, foreign} {
+		if err := os.Chtimes(filename, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := store.Sweep(); err != nil {
+		t.Fatalf("Sweep failed. Err: %v", err)
+	}
+
+	if _, err := store.Get("old"); err != ErrBlobNotFound {
+		t.Fatalf("expired body still stored, err = %v", err)
+	}
+	if _, err := store.Get("new"); err != nil {
+		t.Fatalf("fresh body swept. Err: %v", err)
+	}
+	if _, err := os.Stat(partial); !os.IsNotExist(err) {
+		t.Fatalf("partial write not swept")
+	}
+	if _, err := os.Stat(foreign); err != nil {
+		t.Fatalf("foreign file swept")
+	}
+}
+
+func TestNewFileStoreValidation(t *testing.T) {
+	if _, err := NewFileStore(t.TempDir(), -time.Second); err != ErrInvalidTTL {
+		t.Fatalf("NewFileStore() err = %v, want %v", err, ErrInvalidTTL)
+	}
+}
+
+func TestClosed(t *testing.T) {
+	store, _ := newStore(t, 0)
+	store.Close()
+	store.Close()
+
+	if err := store.Put("k", nil); err != ErrStoreClosed {
+		t.Fatalf("Put() err = %v, want %v", err, ErrStoreClosed)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	body := []byte("stored body")
+
+	tests := []struct {
+		name   string
+		body   []byte
+		size   int64
+		digest string
+		err    error
+	}{
+		{"matches", body, int64(len(body)), Digest(body), nil},
+		{"truncated", body[:5], int64(len(body)), Digest(body), ErrSizeMismatch},
+		{"replaced with same size", []byte("other body!"), int64(len(body)), Digest(body), ErrDigestMismatch},
+		{"empty", []byte{}, 0, Digest(nil), nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := Check(tt.body, tt.size, tt.digest); err != tt.err {
+				t.Fatalf("Check() err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestClaimError(t *testing.T) {
+	err := error(&ClaimError{Key: "k", MessageId: "m", Err: ErrSizeMismatch})
+
+	if !errors.Is(err, ErrClaimCheckFailed) {
+		t.Fatalf("does not match ErrClaimCheckFailed")
+	}
+	if !errors.Is(err, ErrSizeMismatch) {
+		t.Fatalf("does not unwrap to the cause")
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package claimcheck
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+)
+
+const (
+	// HeaderClaimCheck key of the stored body, the message itself is sent empty
+	HeaderClaimCheck string = "x-claim-check"
+
+	// HeaderClaimCheckSize size of the stored body
+	HeaderClaimCheckSize string = "x-claim-check-size"
+
+	// HeaderClaimCheckDigest hex SHA-256 of the stored body
+	HeaderClaimCheckDigest string = "x-claim-check-sha256"
+
+	// DefaultThreshold bodies at or above this size are stored
+	DefaultThreshold int = 4 * 1024 * 1024
+
+	// DefaultDirectory where the file store keeps bodies when none is given
+	DefaultDirectory string = ".rabbitmq-manager/blobs"
+
+	// DefaultTTL how long the file store keeps a body nobody deleted
+	DefaultTTL time.Duration = 24 * time.Hour
+
+	fileExtension string = ".blob"
+	tmpExtension  string = common.TmpExtension
+)
+
+// Headers every header of a claim, in the order they are signed
+var Headers = []string{HeaderClaimCheck, HeaderClaimCheckSize, HeaderClaimCheckDigest}
+
+var (
+	// ErrInvalidBlobKey the blob key is empty
+	ErrInvalidBlobKey = errors.New("the blob key is empty")
+
+	// ErrBlobNotFound no blob is stored under the key
+	ErrBlobNotFound = errors.New("no blob is stored under the key")
+
+	// ErrInvalidTTL the TTL cannot be negative
+	ErrInvalidTTL = errors.New("the TTL cannot be negative")
+
+	// ErrMalformedClaim the claim check headers are malformed
+	ErrMalformedClaim = errors.New("the claim check headers are malformed")
+
+	// ErrSizeMismatch the stored body does not have the claimed size
+	ErrSizeMismatch = errors.New("the stored body does not have the claimed size")
+
+	// ErrDigestMismatch the stored body does not match the claimed digest
+	ErrDigestMismatch = errors.New("the stored body does not match the claimed digest")
+
+	// ErrClaimCheckFailed the stored body could not be fetched
+	ErrClaimCheckFailed = errors.New("the stored body could not be fetched")
+
+	// ErrStoreClosed the store has been closed
+	ErrStoreClosed = errors.New("the store has been closed")
+)
+
+/*
+	Returned when the body of a claim checked message cannot be fetched or does
+	not match its claim, matches ErrClaimCheckFailed and unwraps to the cause
+*/
+type ClaimError struct {
+	Key       string
+	MessageId string
+	Err       error
+}
+
+func (e *ClaimError) Error() string {
+	return fmt.Sprintf("%v (key: %s, id: %s): %v", ErrClaimCheckFailed, e.Key, e.MessageId, e.Err)
+}
+
+func (e *ClaimError) Is(target error) bool {
+	return target == ErrClaimCheckFailed
+}
+
+func (e *ClaimError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,175 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package claimcheck
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	klog "k8s.io/klog/v2"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+)
+
+/*
+	Opens a store in directory. A zero ttl uses DefaultTTL.
+*/
+func NewFileStore(directory string, ttl time.Duration) (*FileStore, error) {
+	if directory == "" {
+		directory = DefaultDirectory
+	}
+	if ttl < 0 {
+		return nil, ErrInvalidTTL
+	}
+	if ttl == 0 {
+		ttl = DefaultTTL
+	}
+
+	err := os.MkdirAll(directory, 0700)
+	if err != nil {
+		klog.V(1).Infof("MkdirAll %s failed. Err: %v\n", directory, err)
+		return nil, err
+	}
+
+	store := &FileStore{
+		directory: directory,
+		ttl:       ttl,
+		stopChan:  make(chan struct{}),
+	}
+
+	go store.sweepLoop()
+
+	return store, nil
+}
+
+func (f *FileStore) filename(key string) string {
+	return filepath.Join(f.directory, url.PathEscape(key)+fileExtension)
+}
+
+func (f *FileStore) Put(key string, data []byte) error {
+	if key == "" {
+		return ErrInvalidBlobKey
+	}
+
+	f.mu.Lock()
+	closed := f.closed
+	f.mu.Unlock()
+	if closed {
+		return ErrStoreClosed
+	}
+
+	err := common.WriteFileAtomic(f.filename(key), data, 0600)
+	if err != nil {
+		klog.V(1).Infof("WriteFileAtomic %s failed. Err: %v\n", key, err)
+		return err
+	}
+
+	return nil
+}
+
+func (f *FileStore) Get(key string) ([]byte, error) {
+	if key == "" {
+		return nil, ErrInvalidBlobKey
+	}
+
+	data, err := os.ReadFile(f.filename(key))
+	if os.IsNotExist(err) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		klog.V(1).Infof("ReadFile %s failed. Err: %v\n", key, err)
+		return nil, err
+	}
+
+	return data, nil
+}
+
+/*
+	Removes a body, deleting one that is already gone is not an error
+*/
+func (f *FileStore) Delete(key string) error {
+	if key == "" {
+		return ErrInvalidBlobKey
+	}
+
+	err := os.Remove(f.filename(key))
+	if err != nil && !os.IsNotExist(err) {
+		klog.V(1).Infof("Remove %s failed. Err: %v\n", key, err)
+		return err
+	}
+
+	return nil
+}
+
+/*
+	Removes every body, and any leftover partial write, older than the TTL
+*/
+func (f *FileStore) Sweep() error {
+	entries, err := os.ReadDir(f.directory)
+	if err != nil {
+		klog.V(1).Infof("ReadDir %s failed. Err: %v\n", f.directory, err)
+		return err
+	}
+
+	cutoff := time.Now().Add(-f.ttl)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !(strings.HasSuffix(name, fileExtension) || strings.HasSuffix(name, tmpExtension)) {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil || !info.ModTime().Before(cutoff) {
+			continue
+		}
+
+		err = os.Remove(filepath.Join(f.directory, name))
+		if err != nil && !os.IsNotExist(err) {
+			klog.V(1).Infof("Remove %s failed. Err: %v\n", name, err)
+			continue
+		}
+		klog.V(4).Infof("Expired blob %s\n", name)
+	}
+
+	return nil
+}
+
+func (f *FileStore) sweepLoop() {
+	// sweep often enough that nothing outlives its TTL by much
+	interval := f.ttl / 10
+	if interval < time.Second {
+		interval = time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			f.Sweep()
+		case <-f.stopChan:
+			return
+		}
+	}
+}
+
+/*
+	Stops the background sweep, stored bodies are kept
+*/
+func (f *FileStore) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.closed {
+		return nil
+	}
+	f.closed = true
+	close(f.stopChan)
+
+	return nil
+}
@@ -0,0 +1,23 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package claimcheck
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+	BlobStore keeping each body in its own file. Bodies older than the TTL are
+	swept in the background, which covers subscribers that never ack and
+	fanout queues where no single subscriber may delete.
+*/
+type FileStore struct {
+	directory string
+	ttl       time.Duration
+
+	stopChan chan struct{}
+	closed   bool
+	mu       sync.Mutex
+}
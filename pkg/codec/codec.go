@@ -0,0 +1,160 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package codec
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/json"
+	"mime"
+	"reflect"
+	"sync"
+
+	msgpack "github.com/vmihailenco/msgpack/v5"
+	proto "google.golang.org/protobuf/proto"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+var (
+	registry = map[string]interfaces.Codec{
+		ContentTypeJSON:     NewJSONCodec(),
+		ContentTypeProtobuf: NewProtobufCodec(),
+		ContentTypeMsgPack:  NewMsgPackCodec(),
+		ContentTypeGob:      NewGobCodec(),
+	}
+	registryMu sync.RWMutex
+)
+
+/*
+	Makes a codec available to ForContentType, replacing any codec registered for
+	the same content type
+*/
+func Register(codec interfaces.Codec) error {
+	if codec == nil || codec.ContentType() == "" {
+		return ErrInvalidCodec
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	registry[codec.ContentType()] = codec
+	return nil
+}
+
+/*
+	Looks up the codec for a content type, ignoring parameters such as charset
+*/
+func ForContentType(contentType string) (interfaces.Codec, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrUnknownContentType
+	}
+
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	codec, ok := registry[mediaType]
+	if !ok {
+		return nil, ErrUnknownContentType
+	}
+	return codec, nil
+}
+
+func NewJSONCodec() *JSONCodec {
+	return &JSONCodec{}
+}
+
+func (c *JSONCodec) ContentType() string {
+	return ContentTypeJSON
+}
+
+func (c *JSONCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (c *JSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+func NewProtobufCodec() *ProtobufCodec {
+	return &ProtobufCodec{}
+}
+
+func (c *ProtobufCodec) ContentType() string {
+	return ContentTypeProtobuf
+}
+
+func (c *ProtobufCodec) Marshal(v interface{}) ([]byte, error) {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return nil, ErrNotProtoMessage
+	}
+	return proto.Marshal(message)
+}
+
+/*
+	Accepts a message or a pointer to a message pointer, which is allocated
+*/
+func (c *ProtobufCodec) Unmarshal(data []byte, v interface{}) error {
+	if message, ok := v.(proto.Message); ok {
+		return proto.Unmarshal(data, message)
+	}
+
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.IsNil() || value.Elem().Kind() != reflect.Ptr {
+		return ErrNotProtoMessage
+	}
+
+	target := reflect.New(value.Elem().Type().Elem())
+	message, ok := target.Interface().(proto.Message)
+	if !ok {
+		return ErrNotProtoMessage
+	}
+
+	err := proto.Unmarshal(data, message)
+	if err != nil {
+		return err
+	}
+	value.Elem().Set(target)
+
+	return nil
+}
+
+func NewMsgPackCodec() *MsgPackCodec {
+	return &MsgPackCodec{}
+}
+
+func (c *MsgPackCodec) ContentType() string {
+	return ContentTypeMsgPack
+}
+
+func (c *MsgPackCodec) Marshal(v interface{}) ([]byte, error) {
+	return msgpack.Marshal(v)
+}
+
+func (c *MsgPackCodec) Unmarshal(data []byte, v interface{}) error {
+	return msgpack.Unmarshal(data, v)
+}
+
+func NewGobCodec() *GobCodec {
+	return &GobCodec{}
+}
+
+func (c *GobCodec) ContentType() string {
+	return ContentTypeGob
+}
+
+func (c *GobCodec) Marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(v)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (c *GobCodec) Unmarshal(data []byte, v interface{}) error {
+	return gob.NewDecoder(bytes.NewReader(data)).Decode(v)
+}
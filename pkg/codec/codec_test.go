@@ -0,0 +1,164 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package codec
+
+import (
+	"reflect"
+	"testing"
+
+	proto "google.golang.org/protobuf/proto"
+	wrapperspb "google.golang.org/protobuf/types/known/wrapperspb"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+type order struct {
+	Id    string
+	Total int
+	Items []string
+}
+
+func TestRoundTrip(t *testing.T) {
+	value := order{Id: "a", Total: 3, Items: []string{"x", "y"}}
+
+	tests := []struct {
+		name  string
+		codec interfaces.Codec
+	}{
+		{"json", NewJSONCodec()},
+		{"msgpack", NewMsgPackCodec()},
+		{"gob", NewGobCodec()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.codec.Marshal(value)
+			if err != nil {
+				t.Fatalf("Marshal failed. Err: %v", err)
+			}
+
+			var got order
+			err = tt.codec.Unmarshal(data, &got)
+			if err != nil {
+				t.Fatalf("Unmarshal failed. Err: %v", err)
+			}
+			if !reflect.DeepEqual(got, value) {
+				t.Fatalf("round trip returned %+v, want %+v", got, value)
+			}
+		})
+	}
+}
+
+func TestProtobuf(t *testing.T) {
+	c := NewProtobufCodec()
+
+	data, err := c.Marshal(wrapperspb.String("hello"))
+	if err != nil {
+		t.Fatalf("Marshal failed. Err: %v", err)
+	}
+
+	// into an existing message
+	message := &wrapperspb.StringValue{}
+	err = c.Unmarshal(data, message)
+	if err != nil || message.GetValue() != "hello" {
+		t.Fatalf("Unmarshal returned %v. Err: %v", message, err)
+	}
+
+	// into a nil message pointer, as the typed subscriber does
+	var allocated *wrapperspb.StringValue
+	err = c.Unmarshal(data, &allocated)
+	if err != nil || allocated.GetValue() != "hello" {
+		t.Fatalf("Unmarshal returned %v. Err: %v", allocated, err)
+	}
+	if !proto.Equal(allocated, message) {
+		t.Fatalf("messages differ")
+	}
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"struct", &order{}},
+		{"nil", nil},
+		{"pointer to struct pointer", new(*order)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.Unmarshal(data, tt.value); err != ErrNotProtoMessage {
+				t.Fatalf("Unmarshal() err = %v, want %v", err, ErrNotProtoMessage)
+			}
+		})
+	}
+	if _, err := c.Marshal(order{}); err != ErrNotProtoMessage {
+		t.Fatalf("Marshal() err = %v, want %v", err, ErrNotProtoMessage)
+	}
+}
+
+type textCodec struct {
+	contentType string
+}
+
+func (c textCodec) ContentType() string {
+	return c.contentType
+}
+
+func (c textCodec) Marshal(v interface{}) ([]byte, error) {
+	return []byte(v.(string)), nil
+}
+
+func (c textCodec) Unmarshal(data []byte, v interface{}) error {
+	*v.(*string) = string(data)
+	return nil
+}
+
+func TestForContentType(t *testing.T) {
+	tests := []struct {
+		contentType string
+		want        string
+		err         error
+	}{
+		{ContentTypeJSON, ContentTypeJSON, nil},
+		{"application/json; charset=utf-8", ContentTypeJSON, nil},
+		{"Application/JSON", ContentTypeJSON, nil},
+		{ContentTypeProtobuf, ContentTypeProtobuf, nil},
+		{ContentTypeMsgPack, ContentTypeMsgPack, nil},
+		{ContentTypeGob, ContentTypeGob, nil},
+		{"text/plain", "", ErrUnknownContentType},
+		{"", "", ErrUnknownContentType},
+		{";;", "", ErrUnknownContentType},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.contentType, func(t *testing.T) {
+			c, err := ForContentType(tt.contentType)
+			if err != tt.err {
+				t.Fatalf("ForContentType() err = %v, want %v", err, tt.err)
+			}
+			if err == nil && c.ContentType() != tt.want {
+				t.Fatalf("ForContentType() = %s, want %s", c.ContentType(), tt.want)
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if err := Register(nil); err != ErrInvalidCodec {
+		t.Fatalf("Register(nil) err = %v, want %v", err, ErrInvalidCodec)
+	}
+	if err := Register(textCodec{}); err != ErrInvalidCodec {
+		t.Fatalf("Register() err = %v, want %v", err, ErrInvalidCodec)
+	}
+
+	err := Register(textCodec{contentType: "text/x-test"})
+	if err != nil {
+		t.Fatalf("Register failed. Err: %v", err)
+	}
+	c, err := ForContentType("text/x-test; charset=utf-8")
+	if err != nil {
+		t.Fatalf("ForContentType failed. Err: %v", err)
+	}
+	if _, ok := c.(textCodec); !ok {
+		t.Fatalf("ForContentType returned %T", c)
+	}
+}
@@ -0,0 +1,33 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package codec
+
+import (
+	"errors"
+)
+
+const (
+	// ContentTypeJSON content type of the JSON codec
+	ContentTypeJSON string = "application/json"
+
+	// ContentTypeProtobuf content type of the Protocol Buffers codec
+	ContentTypeProtobuf string = "application/x-protobuf"
+
+	// ContentTypeMsgPack content type of the MessagePack codec
+	ContentTypeMsgPack string = "application/msgpack"
+
+	// ContentTypeGob content type of the gob codec
+	ContentTypeGob string = "application/x-gob"
+)
+
+var (
+	// ErrNotProtoMessage the value is not a Protocol Buffers message
+	ErrNotProtoMessage = errors.New("the value is not a Protocol Buffers message")
+
+	// ErrUnknownContentType no codec is registered for the content type
+	ErrUnknownContentType = errors.New("no codec is registered for the content type")
+
+	// ErrInvalidCodec the codec is nil or has no content type
+	ErrInvalidCodec = errors.New("the codec is nil or has no content type")
+)
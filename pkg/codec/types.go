@@ -0,0 +1,15 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package codec
+
+/*
+	Built-in codecs
+*/
+type JSONCodec struct{}
+
+type ProtobufCodec struct{}
+
+type MsgPackCodec struct{}
+
+type GobCodec struct{}
@@ -15,7 +15,100 @@ const (
 	ExchangeHeaders = "headers"
 )
 
+/*
+	Queue Types
+*/
+const (
+	QueueClassic = "classic"
+	QueueQuorum  = "quorum"
+	QueueStream  = "stream"
+)
+
+/*
+	Queue Overflow Behaviour
+*/
+const (
+	OverflowDropHead         = "drop-head"
+	OverflowRejectPublish    = "reject-publish"
+	OverflowRejectPublishDLX = "reject-publish-dlx"
+)
+
+/*
+	Queue Arguments
+*/
+const (
+	ArgQueueType      = "x-queue-type"
+	ArgMaxLength      = "x-max-length"
+	ArgMaxLengthBytes = "x-max-length-bytes"
+	ArgOverflow       = "x-overflow"
+	ArgMessageTTL     = "x-message-ttl"
+	ArgExpires        = "x-expires"
+	ArgDeliveryLimit  = "x-delivery-limit"
+	ArgQueueMode      = "x-queue-mode"
+
+	QueueModeLazy = "lazy"
+)
+
+/*
+	Stream Offsets
+*/
+const (
+	ArgStreamOffset = "x-stream-offset"
+
+	StreamOffsetFirst = "first"
+	StreamOffsetLast  = "last"
+	StreamOffsetNext  = "next"
+)
+
+/*
+	Routing Keys
+*/
+const (
+	MaxRoutingKeyLength = 255
+
+	TopicWordSeparator = "."
+	TopicWildcardWord  = "*"
+	TopicWildcardWords = "#"
+)
+
+/*
+	Headers Exchange
+*/
+const (
+	ArgMatch = "x-match"
+
+	HeaderMatchAll = "all"
+	HeaderMatchAny = "any"
+
+	// headers with this prefix are ignored when matching
+	ReservedHeaderPrefix = "x-"
+)
+
+/*
+	Reserved Headers
+*/
+const (
+	// HeaderPublishSequence set by a confirming publisher on mandatory messages to
+	// match a returned message to its publish. It is signed with the message and
+	// stripped by the subscriber before the handler runs.
+	HeaderPublishSequence = "x-publish-sequence"
+)
+
+/*
+	Files
+*/
+const (
+	// TmpExtension of the temporary file WriteFileAtomic renames into place
+	TmpExtension = ".tmp"
+)
+
 var (
 	// ErrUnresolvedRabbitError unresolvable rabbit error
 	ErrUnresolvedRabbitError = errors.New("unresolvable rabbit error")
+
+	// ErrInvalidRoutingKey the routing key is too long or not valid UTF-8
+	ErrInvalidRoutingKey = errors.New("the routing key is too long or not valid UTF-8")
+
+	// ErrInvalidBindingKey the binding key is not a valid pattern for the exchange type
+	ErrInvalidBindingKey = errors.New("the binding key is not a valid pattern for the exchange type")
 )
@@ -4,6 +4,14 @@
 package common
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"unicode/utf8"
+
 	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
 )
 
@@ -21,3 +29,158 @@ func ExchangeTypeToString(exchangeType interfaces.ExchangeType) string {
 		return ExchangeDirect
 	}
 }
+
+func QueueTypeToString(queueType interfaces.QueueType) string {
+	switch queueType {
+	case interfaces.QueueTypeClassic:
+		return QueueClassic
+	case interfaces.QueueTypeQuorum:
+		return QueueQuorum
+	case interfaces.QueueTypeStream:
+		return QueueStream
+	default:
+		return QueueClassic
+	}
+}
+
+func OverflowTypeToString(overflowType interfaces.OverflowType) string {
+	switch overflowType {
+	case interfaces.OverflowTypeDropHead:
+		return OverflowDropHead
+	case interfaces.OverflowTypeRejectPublish:
+		return OverflowRejectPublish
+	case interfaces.OverflowTypeRejectPublishDLX:
+		return OverflowRejectPublishDLX
+	default:
+		return ""
+	}
+}
+
+func StreamOffsetTypeToString(offsetType interfaces.StreamOffsetType) string {
+	switch offsetType {
+	case interfaces.StreamOffsetTypeFirst:
+		return StreamOffsetFirst
+	case interfaces.StreamOffsetTypeLast:
+		return StreamOffsetLast
+	default:
+		return StreamOffsetNext
+	}
+}
+
+func HeaderMatchTypeToString(matchType interfaces.HeaderMatchType) string {
+	switch matchType {
+	case interfaces.HeaderMatchTypeAny:
+		return HeaderMatchAny
+	default:
+		return HeaderMatchAll
+	}
+}
+
+func ValidateRoutingKey(key string) error {
+	if len(key) > MaxRoutingKeyLength || !utf8.ValidString(key) {
+		return ErrInvalidRoutingKey
+	}
+	return nil
+}
+
+/*
+	Topic binding keys are dot separated words where * matches exactly one word
+	and # matches zero or more words. Wildcards must make up a whole word.
+*/
+func ValidateBindingKey(exchangeType interfaces.ExchangeType, key string) error {
+	err := ValidateRoutingKey(key)
+	if err != nil {
+		return err
+	}
+
+	if exchangeType != interfaces.ExchangeTypeTopic {
+		return nil
+	}
+
+	for _, word := range strings.Split(key, TopicWordSeparator) {
+		if word == TopicWildcardWord || word == TopicWildcardWords {
+			continue
+		}
+		if strings.Contains(word, TopicWildcardWord) || strings.Contains(word, TopicWildcardWords) {
+			return ErrInvalidBindingKey
+		}
+	}
+
+	return nil
+}
+
+/*
+	Writes the file through a temporary file that is synced and then renamed into
+	place, so a crash leaves either the old or the new contents and never a
+	partial file. The directory is synced too so the rename itself is durable.
+*/
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	tmpFilename := filename + TmpExtension
+
+	file, err := os.OpenFile(tmpFilename, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	_, err = file.Write(data)
+	if err == nil {
+		err = file.Sync()
+	}
+	closeErr := file.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+
+	err = os.Rename(tmpFilename, filename)
+	if err != nil {
+		os.Remove(tmpFilename)
+		return err
+	}
+
+	return syncDirectory(filepath.Dir(filename))
+}
+
+func syncDirectory(directory string) error {
+	dir, err := os.Open(directory)
+	if err != nil {
+		return err
+	}
+	defer dir.Close()
+
+	return dir.Sync()
+}
+
+func NewConsumerTag(name string) (string, error) {
+	suffix, err := randomHex(8)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s-%s", name, suffix), nil
+}
+
+/*
+	Returns a random (version 4) UUID
+*/
+func NewMessageId() (string, error) {
+	uuid := make([]byte, 16)
+	_, err := rand.Read(uuid)
+	if err != nil {
+		return "", err
+	}
+	uuid[6] = (uuid[6] & 0x0f) | 0x40
+	uuid[8] = (uuid[8] & 0x3f) | 0x80
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", uuid[0:4], uuid[4:6], uuid[6:8], uuid[8:10], uuid[10:16]), nil
+}
+
+func randomHex(size int) (string, error) {
+	buf := make([]byte, size)
+	_, err := rand.Read(buf)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,124 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewMessageId(t *testing.T) {
+	seen := make(map[string]bool)
+	for i := 0; i < 1000; i++ {
+		id, err := NewMessageId()
+		if err != nil {
+			t.Fatalf("NewMessageId failed. Err: %v", err)
+		}
+		if !uuidPattern.MatchString(id) {
+			t.Fatalf("%s is not a version 4 UUID", id)
+		}
+		if seen[id] {
+			t.Fatalf("%s generated twice", id)
+		}
+		seen[id] = true
+	}
+}
+
+func TestNewConsumerTag(t *testing.T) {
+	tag, err := NewConsumerTag("orders")
+	if err != nil {
+		t.Fatalf("NewConsumerTag failed. Err: %v", err)
+	}
+	if !strings.HasPrefix(tag, "orders-") || len(tag) != len("orders-")+16 {
+		t.Fatalf("unexpected consumer tag %s", tag)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "state")
+
+	for _, data := range []string{"first", "second, and longer", ""} {
+		err := WriteFileAtomic(filename, []byte(data), 0600)
+		if err != nil {
+			t.Fatalf("WriteFileAtomic failed. Err: %v", err)
+		}
+
+		got, err := os.ReadFile(filename)
+		if err != nil || string(got) != data {
+			t.Fatalf("file holds %q, want %q. Err: %v", got, data, err)
+		}
+	}
+
+	if _, err := os.Stat(filename + TmpExtension); !os.IsNotExist(err) {
+		t.Fatalf("temporary file left behind")
+	}
+}
+
+func TestWriteFileAtomicFailureKeepsOldContents(t *testing.T) {
+	dir := t.TempDir()
+	filename := filepath.Join(dir, "state")
+
+	err := WriteFileAtomic(filename, []byte("old"), 0600)
+	if err != nil {
+		t.Fatalf("WriteFileAtomic failed. Err: %v", err)
+	}
+
+	// a directory in the way of the temporary file makes the write fail
+	err = os.Mkdir(filename+TmpExtension, 0700)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := WriteFileAtomic(filename, []byte("new"), 0600); err == nil {
+		t.Fatalf("WriteFileAtomic succeeded over a directory")
+	}
+
+	got, err := os.ReadFile(filename)
+	if err != nil || string(got) != "old" {
+		t.Fatalf("file holds %q after a failed write. Err: %v", got, err)
+	}
+}
+
+func TestValidateBindingKey(t *testing.T) {
+	tests := []struct {
+		name         string
+		exchangeType interfaces.ExchangeType
+		key          string
+		err          error
+	}{
+		{"plain words", interfaces.ExchangeTypeTopic, "orders.eu.created", nil},
+		{"empty key", interfaces.ExchangeTypeTopic, "", nil},
+		{"single word wildcard", interfaces.ExchangeTypeTopic, "orders.*.created", nil},
+		{"multi word wildcard", interfaces.ExchangeTypeTopic, "orders.#", nil},
+		{"wildcards only", interfaces.ExchangeTypeTopic, "#", nil},
+		{"both wildcards", interfaces.ExchangeTypeTopic, "*.orders.#", nil},
+		{"empty segments", interfaces.ExchangeTypeTopic, "orders..created", nil},
+		{"wildcard inside a word", interfaces.ExchangeTypeTopic, "orders.eu*.created", ErrInvalidBindingKey},
+		{"multi word wildcard inside a word", interfaces.ExchangeTypeTopic, "orders#", ErrInvalidBindingKey},
+		{"doubled wildcard", interfaces.ExchangeTypeTopic, "orders.**", ErrInvalidBindingKey},
+		{"mixed wildcards", interfaces.ExchangeTypeTopic, "orders.*#", ErrInvalidBindingKey},
+		{"too long", interfaces.ExchangeTypeTopic, strings.Repeat("a", MaxRoutingKeyLength+1), ErrInvalidRoutingKey},
+		{"longest allowed", interfaces.ExchangeTypeTopic, strings.Repeat("a", MaxRoutingKeyLength), nil},
+		{"invalid UTF-8", interfaces.ExchangeTypeTopic, "orders.\xff", ErrInvalidRoutingKey},
+		{"direct keys are literal", interfaces.ExchangeTypeDirect, "orders.eu*", nil},
+		{"direct keys are still checked", interfaces.ExchangeTypeDirect, "\xff", ErrInvalidRoutingKey},
+		{"fanout ignores the pattern", interfaces.ExchangeTypeFanout, "orders#", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateBindingKey(tt.exchangeType, tt.key)
+			if err != tt.err {
+				t.Fatalf("ValidateBindingKey(%q) err = %v, want %v", tt.key, err, tt.err)
+			}
+		})
+	}
+}
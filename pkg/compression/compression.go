@@ -0,0 +1,123 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package compression
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+
+	s2 "github.com/klauspost/compress/s2"
+	zstd "github.com/klauspost/compress/zstd"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+// EncodeAll is safe for concurrent use so one encoder serves every publisher
+var zstdEncoder, _ = zstd.NewWriter(nil)
+
+func TypeToEncoding(compressionType interfaces.CompressionType) string {
+	switch compressionType {
+	case interfaces.CompressionTypeGzip:
+		return EncodingGzip
+	case interfaces.CompressionTypeZstd:
+		return EncodingZstd
+	case interfaces.CompressionTypeSnappy:
+		return EncodingSnappy
+	default:
+		return ""
+	}
+}
+
+/*
+	Reports whether the content-encoding is one this package can decompress
+*/
+func IsSupported(encoding string) bool {
+	switch encoding {
+	case EncodingGzip, EncodingZstd, EncodingSnappy:
+		return true
+	default:
+		return false
+	}
+}
+
+func Compress(compressionType interfaces.CompressionType, data []byte) ([]byte, error) {
+	switch compressionType {
+	case interfaces.CompressionTypeGzip:
+		var buf bytes.Buffer
+		writer := gzip.NewWriter(&buf)
+		_, err := writer.Write(data)
+		if err != nil {
+			return nil, err
+		}
+		err = writer.Close()
+		if err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	case interfaces.CompressionTypeZstd:
+		return zstdEncoder.EncodeAll(data, nil), nil
+	case interfaces.CompressionTypeSnappy:
+		return s2.EncodeSnappy(nil, data), nil
+	default:
+		return nil, ErrUnknownCompression
+	}
+}
+
+/*
+	Decompresses a body, refusing to expand it beyond maxSize so a small message
+	cannot exhaust memory
+*/
+func Decompress(encoding string, data []byte, maxSize int64) ([]byte, error) {
+	if maxSize == 0 {
+		maxSize = DefaultMaxDecompressedSize
+	}
+
+	switch encoding {
+	case EncodingGzip:
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer reader.Close()
+		return readLimited(reader, maxSize)
+	case EncodingZstd:
+		decoder, err := zstd.NewReader(bytes.NewReader(data),
+			zstd.WithDecoderConcurrency(1),
+			zstd.WithDecoderMaxMemory(uint64(maxSize)))
+		if err != nil {
+			return nil, err
+		}
+		defer decoder.Close()
+
+		data, err := readLimited(decoder, maxSize)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) || errors.Is(err, zstd.ErrWindowSizeExceeded) {
+			return nil, ErrDecompressedTooLarge
+		}
+		return data, err
+	case EncodingSnappy:
+		size, err := s2.DecodedLen(data)
+		if err != nil {
+			return nil, err
+		}
+		if int64(size) > maxSize {
+			return nil, ErrDecompressedTooLarge
+		}
+		return s2.Decode(nil, data)
+	default:
+		return nil, ErrUnknownCompression
+	}
+}
+
+func readLimited(reader io.Reader, maxSize int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(reader, maxSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > maxSize {
+		return nil, ErrDecompressedTooLarge
+	}
+	return data, nil
+}
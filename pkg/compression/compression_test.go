@@ -0,0 +1,126 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package compression
+
+import (
+	"bytes"
+	"math/rand"
+	"testing"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+var compressionTypes = []interfaces.CompressionType{
+	interfaces.CompressionTypeGzip,
+	interfaces.CompressionTypeZstd,
+	interfaces.CompressionTypeSnappy,
+}
+
+func TestRoundTrip(t *testing.T) {
+	random := make([]byte, 4096)
+	rand.New(rand.NewSource(1)).Read(random)
+
+	bodies := map[string][]byte{
+		"empty":      {},
+		"text":       []byte("hello world"),
+		"repetitive": bytes.Repeat([]byte("abc"), 10000),
+		"random":     random,
+	}
+
+	for _, compressionType := range compressionTypes {
+		encoding := TypeToEncoding(compressionType)
+		if !IsSupported(encoding) {
+			t.Fatalf("%s is not supported", encoding)
+		}
+
+		for name, body := range bodies {
+			t.Run(encoding+"/"+name, func(t *testing.T) {
+				compressed, err := Compress(compressionType, body)
+				if err != nil {
+					t.Fatalf("Compress failed. Err: %v", err)
+				}
+
+				got, err := Decompress(encoding, compressed, 0)
+				if err != nil {
+					t.Fatalf("Decompress failed. Err: %v", err)
+				}
+				if !bytes.Equal(got, body) {
+					t.Fatalf("round trip returned %d bytes, want %d", len(got), len(body))
+				}
+			})
+		}
+	}
+}
+
+func TestDecompressLimit(t *testing.T) {
+	body := bytes.Repeat([]byte{0}, 64*1024)
+
+	tests := []struct {
+		name    string
+		maxSize int64
+		err     error
+	}{
+		{"well under the limit", 1 << 20, nil},
+		{"exactly the limit", int64(len(body)), nil},
+		{"one byte over the limit", int64(len(body)) - 1, ErrDecompressedTooLarge},
+		{"far over the limit", 1024, ErrDecompressedTooLarge},
+	}
+
+	for _, compressionType := range compressionTypes {
+		encoding := TypeToEncoding(compressionType)
+		compressed, err := Compress(compressionType, body)
+		if err != nil {
+			t.Fatalf("Compress failed. Err: %v", err)
+		}
+
+		for _, tt := range tests {
+			t.Run(encoding+"/"+tt.name, func(t *testing.T) {
+				got, err := Decompress(encoding, compressed, tt.maxSize)
+				if err != tt.err {
+					t.Fatalf("Decompress() err = %v, want %v", err, tt.err)
+				}
+				if err == nil && !bytes.Equal(got, body) {
+					t.Fatalf("Decompress returned %d bytes, want %d", len(got), len(body))
+				}
+			})
+		}
+	}
+}
+
+func TestDecompressDefaultLimit(t *testing.T) {
+	// compresses to well under a megabyte but expands past the default limit
+	body := make([]byte, DefaultMaxDecompressedSize+1)
+
+	compressed, err := Compress(interfaces.CompressionTypeGzip, body)
+	if err != nil {
+		t.Fatalf("Compress failed. Err: %v", err)
+	}
+	if _, err := Decompress(EncodingGzip, compressed, 0); err != ErrDecompressedTooLarge {
+		t.Fatalf("Decompress() err = %v, want %v", err, ErrDecompressedTooLarge)
+	}
+}
+
+func TestDecompressInvalid(t *testing.T) {
+	garbage := []byte("this is not compressed")
+
+	for _, encoding := range []string{EncodingGzip, EncodingZstd, EncodingSnappy} {
+		t.Run(encoding, func(t *testing.T) {
+			if _, err := Decompress(encoding, garbage, 0); err == nil {
+				t.Fatalf("Decompress accepted garbage")
+			}
+		})
+	}
+}
+
+func TestUnknownCompression(t *testing.T) {
+	if _, err := Compress(interfaces.CompressionType(99), nil); err != ErrUnknownCompression {
+		t.Fatalf("Compress() err = %v, want %v", err, ErrUnknownCompression)
+	}
+	if _, err := Decompress("br", nil, 0); err != ErrUnknownCompression {
+		t.Fatalf("Decompress() err = %v, want %v", err, ErrUnknownCompression)
+	}
+	if IsSupported("br") || TypeToEncoding(interfaces.CompressionType(99)) != "" {
+		t.Fatalf("unknown compression reported as supported")
+	}
+}
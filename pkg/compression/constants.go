@@ -0,0 +1,33 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package compression
+
+import (
+	"errors"
+)
+
+const (
+	// EncodingGzip content-encoding of gzip compressed bodies
+	EncodingGzip string = "gzip"
+
+	// EncodingZstd content-encoding of zstd compressed bodies
+	EncodingZstd string = "zstd"
+
+	// EncodingSnappy content-encoding of snappy compressed bodies
+	EncodingSnappy string = "snappy"
+
+	// DefaultThreshold bodies smaller than this are not worth compressing
+	DefaultThreshold int = 1024
+
+	// DefaultMaxDecompressedSize largest body a compressed message may expand to
+	DefaultMaxDecompressedSize int64 = 64 * 1024 * 1024
+)
+
+var (
+	// ErrUnknownCompression the compression type is not supported
+	ErrUnknownCompression = errors.New("the compression type is not supported")
+
+	// ErrDecompressedTooLarge the message expands beyond the maximum decompressed size
+	ErrDecompressedTooLarge = errors.New("the message expands beyond the maximum decompressed size")
+)
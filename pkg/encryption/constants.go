@@ -0,0 +1,73 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package encryption
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// HeaderKeyId id of the key encryption key that wrapped the data key
+	HeaderKeyId string = "x-encryption-key-id"
+
+	// HeaderDataKey the wrapped per message data key
+	HeaderDataKey string = "x-encryption-data-key"
+
+	// HeaderAlgorithm the algorithm the body is encrypted with
+	HeaderAlgorithm string = "x-encryption-algorithm"
+
+	// AlgorithmAES256GCM AES-256-GCM with a random data key per message
+	AlgorithmAES256GCM string = "aes-256-gcm"
+
+	dataKeySize int = 32
+)
+
+var (
+	// ErrInvalidKeySize keys must be 16, 24 or 32 bytes long
+	ErrInvalidKeySize = errors.New("keys must be 16, 24 or 32 bytes long")
+
+	// ErrInvalidKeyId the key id is empty
+	ErrInvalidKeyId = errors.New("the key id is empty")
+
+	// ErrNoCurrentKey no key has been selected for encryption
+	ErrNoCurrentKey = errors.New("no key has been selected for encryption")
+
+	// ErrUnknownKey the key id is not known to the key provider
+	ErrUnknownKey = errors.New("the key id is not known to the key provider")
+
+	// ErrUnknownAlgorithm the message is encrypted with an unsupported algorithm
+	ErrUnknownAlgorithm = errors.New("the message is encrypted with an unsupported algorithm")
+
+	// ErrMalformedEnvelope the encryption headers or ciphertext are malformed
+	ErrMalformedEnvelope = errors.New("the encryption headers or ciphertext are malformed")
+
+	// ErrNotEncrypted the message is not encrypted
+	ErrNotEncrypted = errors.New("the message is not encrypted")
+
+	// ErrDecryptionFailed the message could not be decrypted
+	ErrDecryptionFailed = errors.New("the message could not be decrypted")
+)
+
+/*
+	Returned when a message cannot be decrypted, matches ErrDecryptionFailed and
+	unwraps to the cause
+*/
+type DecryptionError struct {
+	KeyId     string
+	MessageId string
+	Err       error
+}
+
+func (e *DecryptionError) Error() string {
+	return fmt.Sprintf("%v (key: %s, id: %s): %v", ErrDecryptionFailed, e.KeyId, e.MessageId, e.Err)
+}
+
+func (e *DecryptionError) Is(target error) bool {
+	return target == ErrDecryptionFailed
+}
+
+func (e *DecryptionError) Unwrap() error {
+	return e.Err
+}
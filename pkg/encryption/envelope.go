@@ -0,0 +1,120 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package encryption
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Encrypts the body with a fresh data key and wraps that data key with the
+	current key of the provider. The key id, algorithm and content-encoding are
+	bound to both as additional data so none of them can be swapped.
+*/
+func Seal(provider interfaces.KeyProvider, contentEncoding string, body []byte) (string, []byte, []byte, error) {
+	keyId, key, err := provider.CurrentKey()
+	if err != nil {
+		return "", nil, nil, err
+	}
+	err = validateKey(key)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	dataKey := make([]byte, dataKeySize)
+	_, err = rand.Read(dataKey)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	aad := additionalData(keyId, contentEncoding)
+	ciphertext, err := seal(dataKey, body, aad)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	wrappedKey, err := seal(key, dataKey, aad)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	return keyId, wrappedKey, ciphertext, nil
+}
+
+/*
+	Unwraps the data key with the named key of the provider and decrypts the body
+*/
+func Open(provider interfaces.KeyProvider, keyId, contentEncoding string, wrappedKey, ciphertext []byte) ([]byte, error) {
+	key, err := provider.GetKey(keyId)
+	if err != nil {
+		return nil, err
+	}
+	err = validateKey(key)
+	if err != nil {
+		return nil, err
+	}
+
+	aad := additionalData(keyId, contentEncoding)
+	dataKey, err := open(key, wrappedKey, aad)
+	if err != nil {
+		return nil, err
+	}
+	return open(dataKey, ciphertext, aad)
+}
+
+/*
+	Length prefixed so no two sets of values share the same bytes
+*/
+func additionalData(keyId, contentEncoding string) []byte {
+	var buf bytes.Buffer
+	for _, value := range []string{keyId, AlgorithmAES256GCM, contentEncoding} {
+		binary.Write(&buf, binary.BigEndian, uint32(len(value)))
+		buf.WriteString(value)
+	}
+	return buf.Bytes()
+}
+
+/*
+	Returns the random nonce followed by the sealed data
+*/
+func seal(key, plaintext, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	_, err = rand.Read(nonce)
+	if err != nil {
+		return nil, err
+	}
+
+	return aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func open(key, sealed, additionalData []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < aead.NonceSize()+aead.Overhead() {
+		return nil, ErrMalformedEnvelope
+	}
+
+	nonce := sealed[:aead.NonceSize()]
+	return aead.Open(nil, nonce, sealed[aead.NonceSize():], additionalData)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,246 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package encryption
+
+import (
+	"bytes"
+	"testing"
+)
+
+func newKey(fill byte, size int) []byte {
+	return bytes.Repeat([]byte{fill}, size)
+}
+
+func newProvider(t *testing.T, ids ...string) *StaticKeyProvider {
+	t.Helper()
+
+	provider := NewStaticKeyProvider()
+	for i, id := range ids {
+		err := provider.AddKey(id, newKey(byte(i+1), 32))
+		if err != nil {
+			t.Fatalf("AddKey failed. Err: %v", err)
+		}
+	}
+	if len(ids) > 0 {
+		err := provider.SetCurrentKey(ids[len(ids)-1])
+		if err != nil {
+			t.Fatalf("SetCurrentKey failed. Err: %v", err)
+		}
+	}
+	return provider
+}
+
+func TestSealOpen(t *testing.T) {
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+	}{
+		{"plain", "", []byte("hello")},
+		{"compressed", "gzip", []byte{0x1f, 0x8b, 0x08}},
+		{"empty", "", []byte{}},
+		{"large", "", bytes.Repeat([]byte("x"), 1<<20)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider := newProvider(t, "k1")
+
+			keyId, wrappedKey, ciphertext, err := Seal(provider, tt.encoding, tt.body)
+			if err != nil {
+				t.Fatalf("Seal failed. Err: %v", err)
+			}
+			if keyId != "k1" {
+				t.Fatalf("sealed with %s, want k1", keyId)
+			}
+			if len(tt.body) > 0 && bytes.Contains(ciphertext, tt.body) {
+				t.Fatalf("ciphertext contains the plaintext")
+			}
+
+			body, err := Open(provider, keyId, tt.encoding, wrappedKey, ciphertext)
+			if err != nil {
+				t.Fatalf("Open failed. Err: %v", err)
+			}
+			if !bytes.Equal(body, tt.body) {
+				t.Fatalf("Open returned %d bytes, want %d", len(body), len(tt.body))
+			}
+		})
+	}
+}
+
+func TestSealUsesFreshDataKeys(t *testing.T) {
+	provider := newProvider(t, "k1")
+
+	_, wrapped1, ciphertext1, err := Seal(provider, "", []byte("same"))
+	if err != nil {
+		t.Fatalf("Seal failed. Err: %v", err)
+	}
+	_, wrapped2, ciphertext2, err := Seal(provider, "", []byte("same"))
+	if err != nil {
+		t.Fatalf("Seal failed. Err: %v", err)
+	}
+	if bytes.Equal(wrapped1, wrapped2) || bytes.Equal(ciphertext1, ciphertext2) {
+		t.Fatalf("two messages share a data key or nonce")
+	}
+}
+
+func TestKeyRotation(t *testing.T) {
+	provider := newProvider(t, "k1")
+
+	oldKeyId, oldWrapped, oldCiphertext, err := Seal(provider, "", []byte("old"))
+	if err != nil {
+		t.Fatalf("Seal failed. Err: %v", err)
+	}
+
+	// rotate: new messages use k2, messages sealed under k1 still open
+	err = provider.AddKey("k2", newKey(9, 32))
+	if err != nil {
+		t.Fatalf("AddKey failed. Err: %v", err)
+	}
+	err = provider.SetCurrentKey("k2")
+	if err != nil {
+		t.Fatalf("SetCurrentKey failed. Err: %v", err)
+	}
+
+	newKeyId, newWrapped, newCiphertext, err := Seal(provider, "", []byte("new"))
+	if err != nil {
+		t.Fatalf("Seal failed. Err: %v", err)
+	}
+	if newKeyId != "k2" {
+		t.Fatalf("sealed with %s after rotation, want k2", newKeyId)
+	}
+
+	for _, tt := range []struct {
+		keyId      string
+		wrapped    []byte
+		ciphertext []byte
+		body       string
+	}{
+		{oldKeyId, oldWrapped, oldCiphertext, "old"},
+		{newKeyId, newWrapped, newCiphertext, "new"},
+	} {
+		body, err := Open(provider, tt.keyId, "", tt.wrapped, tt.ciphertext)
+		if err != nil || string(body) != tt.body {
+			t.Fatalf("Open under %s returned %q. Err: %v", tt.keyId, body, err)
+		}
+	}
+
+	// retiring k1 leaves its messages unreadable
+	provider.RemoveKey("k1")
+	if _, err := Open(provider, oldKeyId, "", oldWrapped, oldCiphertext); err != ErrUnknownKey {
+		t.Fatalf("Open() err = %v, want %v", err, ErrUnknownKey)
+	}
+	if _, err := Open(provider, newKeyId, "", newWrapped, newCiphertext); err != nil {
+		t.Fatalf("Open under k2 failed after removing k1. Err: %v", err)
+	}
+}
+
+func TestOpenTampered(t *testing.T) {
+	provider := newProvider(t, "k1", "k2")
+	// same key bytes as k2 under another id
+	err := provider.AddKey("k2-copy", newKey(2, 32))
+	if err != nil {
+		t.Fatalf("AddKey failed. Err: %v", err)
+	}
+
+	keyId, wrappedKey, ciphertext, err := Seal(provider, "gzip", []byte("payload"))
+	if err != nil {
+		t.Fatalf("Seal failed. Err: %v", err)
+	}
+
+	flip := func(data []byte, i int) []byte {
+		flipped := append([]byte(nil), data...)
+		flipped[i] ^= 0x01
+		return flipped
+	}
+
+	tests := []struct {
+		name       string
+		keyId      string
+		encoding   string
+		wrappedKey []byte
+		ciphertext []byte
+		err        error
+	}{
+		{"other key", "k1", "gzip", wrappedKey, ciphertext, nil},
+		{"key id swapped for the same key", "k2-copy", "gzip", wrappedKey, ciphertext, nil},
+		{"content encoding changed", keyId, "zstd", wrappedKey, ciphertext, nil},
+		{"content encoding removed", keyId, "", wrappedKey, ciphertext, nil},
+		{"ciphertext flipped", keyId, "gzip", wrappedKey, flip(ciphertext, len(ciphertext)-1), nil},
+		{"wrapped key flipped", keyId, "gzip", flip(wrappedKey, 20), ciphertext, nil},
+		{"ciphertext truncated", keyId, "gzip", wrappedKey, ciphertext[:10], ErrMalformedEnvelope},
+		{"unknown key", "k3", "gzip", wrappedKey, ciphertext, ErrUnknownKey},
+	}
+
+	body, err := Open(provider, keyId, "gzip", wrappedKey, ciphertext)
+	if err != nil || string(body) != "payload" {
+		t.Fatalf("Open returned %q. Err: %v", body, err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Open(provider, tt.keyId, tt.encoding, tt.wrappedKey, tt.ciphertext)
+			if err == nil {
+				t.Fatalf("Open accepted a tampered message")
+			}
+			if tt.err != nil && err != tt.err {
+				t.Fatalf("Open() err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestStaticKeyProvider(t *testing.T) {
+	provider := NewStaticKeyProvider()
+
+	if _, _, err := provider.CurrentKey(); err != ErrNoCurrentKey {
+		t.Fatalf("CurrentKey() err = %v, want %v", err, ErrNoCurrentKey)
+	}
+	if _, _, _, err := Seal(provider, "", nil); err != ErrNoCurrentKey {
+		t.Fatalf("Seal() err = %v, want %v", err, ErrNoCurrentKey)
+	}
+
+	tests := []struct {
+		name string
+		id   string
+		key  []byte
+		err  error
+	}{
+		{"aes-128", "a", newKey(1, 16), nil},
+		{"aes-192", "b", newKey(1, 24), nil},
+		{"aes-256", "c", newKey(1, 32), nil},
+		{"short key", "d", newKey(1, 8), ErrInvalidKeySize},
+		{"empty id", "", newKey(1, 32), ErrInvalidKeyId},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := provider.AddKey(tt.id, tt.key); err != tt.err {
+				t.Fatalf("AddKey() err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+
+	if err := provider.SetCurrentKey("missing"); err != ErrUnknownKey {
+		t.Fatalf("SetCurrentKey() err = %v, want %v", err, ErrUnknownKey)
+	}
+
+	// the provider keeps its own copy of the key
+	key := newKey(1, 32)
+	if err := provider.AddKey("copy", key); err != nil {
+		t.Fatalf("AddKey failed. Err: %v", err)
+	}
+	key[0] = 0xff
+	if stored, _ := provider.GetKey("copy"); stored[0] != 1 {
+		t.Fatalf("caller can change a stored key")
+	}
+
+	// removing the current key leaves no current key
+	if err := provider.SetCurrentKey("copy"); err != nil {
+		t.Fatalf("SetCurrentKey failed. Err: %v", err)
+	}
+	provider.RemoveKey("copy")
+	if _, _, err := provider.CurrentKey(); err != ErrNoCurrentKey {
+		t.Fatalf("CurrentKey() err = %v after removing it, want %v", err, ErrNoCurrentKey)
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package encryption
+
+func NewStaticKeyProvider() *StaticKeyProvider {
+	return &StaticKeyProvider{
+		keys: make(map[string][]byte),
+	}
+}
+
+func validateKey(key []byte) error {
+	switch len(key) {
+	case 16, 24, 32:
+		return nil
+	default:
+		return ErrInvalidKeySize
+	}
+}
+
+func (p *StaticKeyProvider) AddKey(id string, key []byte) error {
+	if id == "" {
+		return ErrInvalidKeyId
+	}
+	err := validateKey(key)
+	if err != nil {
+		return err
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.keys[id] = append([]byte(nil), key...)
+	return nil
+}
+
+func (p *StaticKeyProvider) RemoveKey(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	delete(p.keys, id)
+	if p.current == id {
+		p.current = ""
+	}
+}
+
+/*
+	Selects the key new messages are encrypted with
+*/
+func (p *StaticKeyProvider) SetCurrentKey(id string) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if _, ok := p.keys[id]; !ok {
+		return ErrUnknownKey
+	}
+	p.current = id
+	return nil
+}
+
+func (p *StaticKeyProvider) CurrentKey() (string, []byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if p.current == "" {
+		return "", nil, ErrNoCurrentKey
+	}
+	return p.current, p.keys[p.current], nil
+}
+
+func (p *StaticKeyProvider) GetKey(id string) ([]byte, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+	return key, nil
+}
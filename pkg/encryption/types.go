@@ -0,0 +1,18 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package encryption
+
+import (
+	"sync"
+)
+
+/*
+	In memory KeyProvider. Rotate by adding a new key and making it current, the
+	old keys stay available for decryption until they are removed.
+*/
+type StaticKeyProvider struct {
+	current string
+	keys    map[string][]byte
+	mu      sync.RWMutex
+}
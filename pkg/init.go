@@ -46,7 +46,7 @@ func Init(init RabbitInit) {
 
 func New(options interfaces.ManagerOptions) (*interfaces.Manager, error) {
 	manager, err := manager.New(manager.ManagerOptions{
-		&options,
+		ManagerOptions: &options,
 	})
 	if err != nil {
 		return nil, err
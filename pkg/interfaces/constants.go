@@ -14,3 +14,105 @@ const (
 	ExchangeTypeTopic                = 2
 	ExchangeTypeHeaders              = 3
 )
+
+/*
+	Queue Types
+*/
+type QueueType int64
+
+const (
+	QueueTypeClassic QueueType = iota
+	QueueTypeQuorum            = 1
+	QueueTypeStream            = 2
+)
+
+/*
+	Queue Overflow Behaviour
+*/
+type OverflowType int64
+
+const (
+	OverflowTypeDefault          OverflowType = iota
+	OverflowTypeDropHead                      = 1
+	OverflowTypeRejectPublish                 = 2
+	OverflowTypeRejectPublishDLX              = 3
+)
+
+/*
+	Stream Offset Specifications
+*/
+type StreamOffsetType int64
+
+const (
+	StreamOffsetTypeNext      StreamOffsetType = iota
+	StreamOffsetTypeFirst                      = 1
+	StreamOffsetTypeLast                       = 2
+	StreamOffsetTypeNumeric                    = 3
+	StreamOffsetTypeTimestamp                  = 4
+)
+
+/*
+	Headers Exchange Match Types
+*/
+type HeaderMatchType int64
+
+const (
+	HeaderMatchTypeAll HeaderMatchType = iota
+	HeaderMatchTypeAny                 = 1
+)
+
+/*
+	Acknowledgement Outcomes
+*/
+type AckOutcome int64
+
+const (
+	AckOutcomeDefault     AckOutcome = iota
+	AckOutcomeAck                    = 1
+	AckOutcomeNackRequeue            = 2
+	AckOutcomeNackDiscard            = 3
+	AckOutcomeReject                 = 4
+)
+
+/*
+	What a Publisher does when it is over its rate limit
+*/
+type RatePolicy int64
+
+const (
+	RatePolicyBlock RatePolicy = iota
+	RatePolicyFail             = 1
+)
+
+/*
+	Circuit Breaker States
+*/
+type CircuitState int64
+
+const (
+	CircuitStateClosed   CircuitState = iota
+	CircuitStateOpen                  = 1
+	CircuitStateHalfOpen              = 2
+)
+
+/*
+	What a typed Subscriber does with a message it cannot decode
+*/
+type DecodeFailurePolicy int64
+
+const (
+	DecodeFailurePolicyDeadLetter DecodeFailurePolicy = iota
+	DecodeFailurePolicyDrop                           = 1
+)
+
+/*
+	Payload Compression
+*/
+type CompressionType int64
+
+const (
+	CompressionTypeNone   CompressionType = iota
+	CompressionTypeGzip                   = 1
+	CompressionTypeZstd                   = 2
+	CompressionTypeSnappy                 = 3
+)
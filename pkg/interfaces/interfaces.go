@@ -3,17 +3,76 @@
 
 package interfaces
 
+import (
+	"context"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
 /*
 	Configuration Options for Rabbit Subscriber/Publisher
 */
 type ManagerOptions struct {
 	RabbitURI      string
 	DeleteWarnings bool
+
+	// recovery
+	AutoRecover     bool
+	RecoverInterval time.Duration
 }
 
 type PublisherOptions struct {
-	Name string
-	Type ExchangeType
+	Name       string
+	Type       ExchangeType
+	RoutingKey string
+	Headers    amqp.Table
+
+	// message defaults
+	ContentType string
+	AppId       string
+	NoMessageId bool
+	NoTimestamp bool
+
+	// confirms
+	ConfirmMode    bool
+	ConfirmTimeout time.Duration
+
+	// returns
+	Mandatory     bool
+	ReturnHandler *ReturnHandler
+
+	// at-least-once
+	Republish      bool
+	MaxUnconfirmed int
+
+	// batch publishing
+	BatchChannels int
+
+	// rate limiting
+	RateLimit  RateLimit
+	RatePolicy RatePolicy
+
+	// compression
+	Compression          CompressionType
+	CompressionThreshold int
+
+	// encryption
+	KeyProvider *KeyProvider
+
+	// signing
+	Signer        *Signer
+	SignedHeaders []string
+
+	// claim check
+	BlobStore           *BlobStore
+	ClaimCheckThreshold int
+
+	// spool
+	Spool            bool
+	SpoolDirectory   string
+	SpoolSegmentSize int64
+	SpoolMaxBytes    int64
 
 	// init
 	Durable     bool
@@ -29,9 +88,61 @@ type PublisherOptions struct {
 }
 
 type SubscriberOptions struct {
-	Name    string
-	Type    ExchangeType
-	Handler *RabbitMessageHandler
+	Name            string
+	Type            ExchangeType
+	Handler         *RabbitMessageHandler
+	DeliveryHandler *RabbitDeliveryHandler
+	AckHandler      *RabbitAckHandler
+	BatchHandler    *RabbitBatchHandler
+
+	// acknowledgement
+	ErrorOutcome AckOutcome
+	DeferredAck  bool
+
+	// qos
+	PrefetchCount int
+	PrefetchSize  int
+
+	// dispatch
+	Concurrency int
+	OrderingKey OrderingKeyFunc
+
+	// rate limiting
+	RateLimit RateLimit
+
+	// circuit breaker
+	BreakerThreshold    int
+	BreakerOpenInterval time.Duration
+	BreakerProbes       int
+	BreakerHandler      *CircuitBreakerHandler
+
+	// compression
+	MaxDecompressedSize int64
+
+	// encryption
+	KeyProvider       *KeyProvider
+	RequireEncryption bool
+
+	// signing, messages outside the replay window or already seen within it are rejected
+	Verifier              *Verifier
+	RequiredSignedHeaders []string
+	ReplayWindow          time.Duration
+	ReplayCacheSize       int
+
+	// claim check, stored bodies are left for the store to expire unless deleted once acked
+	BlobStore        *BlobStore
+	ClaimCheckDelete bool
+
+	// unreadable deliveries
+	UnreadableHandler *UnreadableHandler
+
+	// batch
+	BatchSize    int
+	BatchTimeout time.Duration
+
+	// routing
+	BindingKeys    []string
+	HeaderBindings []HeaderBinding
 
 	// init
 	Durable     bool
@@ -41,6 +152,27 @@ type SubscriberOptions struct {
 	NoLocal     bool
 	NoAck       bool
 
+	// queue
+	QueueName      string
+	QueueType      QueueType
+	QueueArguments amqp.Table
+	MaxLength      int64
+	MaxLengthBytes int64
+	Overflow       OverflowType
+	MessageTTL     time.Duration
+	QueueExpires   time.Duration
+	DeliveryLimit  int64
+	LazyMode       bool
+
+	// stream
+	StreamOffset          StreamOffsetType
+	StreamOffsetValue     int64
+	StreamOffsetTimestamp time.Time
+	OffsetName            string
+	OffsetStore           *OffsetStore
+	OffsetCommitCount     int
+	OffsetCommitInterval  time.Duration
+
 	// teardown
 	IfUnused       bool
 	IfEmpty        bool
@@ -50,6 +182,199 @@ type SubscriberOptions struct {
 	NoWait bool
 }
 
+/*
+	Binds a subscriber to a headers exchange. Messages are routed when all (or any)
+	of the header values match.
+*/
+type HeaderBinding struct {
+	Match   HeaderMatchType
+	Headers amqp.Table
+}
+
+/*
+	A message along with every AMQP property it can be published with. Unset
+	properties are filled in from the publisher defaults and an empty RoutingKey
+	uses the publisher routing key.
+*/
+type Message struct {
+	RoutingKey string
+
+	// properties
+	Headers         amqp.Table
+	ContentType     string
+	ContentEncoding string
+	DeliveryMode    uint8
+	Priority        uint8
+	CorrelationId   string
+	ReplyTo         string
+	Expiration      string
+	MessageId       string
+	Timestamp       time.Time
+	Type            string
+	UserId          string
+	AppId           string
+
+	Body []byte
+}
+
+/*
+	A received message along with its AMQP properties and delivery metadata
+*/
+type Delivery struct {
+	// delivery
+	ConsumerTag string
+	DeliveryTag uint64
+	Redelivered bool
+	Exchange    string
+	RoutingKey  string
+
+	// properties
+	Headers         amqp.Table
+	ContentType     string
+	ContentEncoding string
+	DeliveryMode    uint8
+	Priority        uint8
+	CorrelationId   string
+	ReplyTo         string
+	Expiration      string
+	MessageId       string
+	Timestamp       time.Time
+	Type            string
+	UserId          string
+	AppId           string
+
+	Body []byte
+
+	// only set when the subscriber uses DeferredAck
+	Acknowledger Acknowledger
+}
+
+/*
+	A mandatory message the broker could not route, along with the reason
+*/
+type Return struct {
+	ReplyCode uint16
+	ReplyText string
+	Exchange  string
+
+	Message Message
+}
+
+/*
+	Future for a publisher confirmation. Wait returns nil once the broker acks the
+	message and an error when it is nacked or the channel closes first.
+*/
+type Confirmation interface {
+	DeliveryTag() uint64
+	Done() <-chan struct{}
+	Wait(ctx context.Context) error
+}
+
+/*
+	Token bucket limits on messages and bytes per second. A zero rate is
+	unlimited and a zero burst allows one second worth.
+*/
+type RateLimit struct {
+	MessagesPerSecond float64
+	MessageBurst      int
+	BytesPerSecond    float64
+	ByteBurst         int
+}
+
+/*
+	Outcome of a single message sent with SendMessages
+*/
+type PublishResult struct {
+	MessageId string
+	Err       error
+}
+
+/*
+	Extracts the key deliveries are ordered by when dispatched to several workers.
+	Deliveries with the same key are processed in order, an empty key can go to
+	any worker.
+*/
+type OrderingKeyFunc func(delivery *Delivery) string
+
+/*
+	Settles a delivery after the handler has returned. Can be used from any
+	goroutine and only the first call has any effect.
+*/
+type Acknowledger interface {
+	Ack() error
+	Nack(requeue bool) error
+	Reject() error
+}
+
+/*
+	Subscriber counters. Acked, Nacked and Rejected count what was sent to the
+	broker, so on a stream or without acknowledgements every delivery is acked.
+*/
+type SubscriberStats struct {
+	Received        uint64
+	Acked           uint64
+	Nacked          uint64
+	Rejected        uint64
+	OutstandingAcks uint64
+
+	// rate limiting
+	Throttled    uint64
+	ThrottleWait time.Duration
+
+	// circuit breaker
+	CircuitState CircuitState
+
+	// compression, the ratio is the uncompressed size over the compressed size
+	Decompressed          uint64
+	DecompressionFailures uint64
+	CompressedBytes       uint64
+	DecompressedBytes     uint64
+	CompressionRatio      float64
+	DecompressionTime     time.Duration
+
+	// encryption
+	Decrypted          uint64
+	DecryptionFailures uint64
+
+	// signing
+	Verified             uint64
+	VerificationFailures uint64
+
+	// claim check
+	ClaimChecked       uint64
+	ClaimCheckFailures uint64
+}
+
+/*
+	Publisher counters
+*/
+type PublisherStats struct {
+	Published uint64
+	Spooled   uint64
+
+	// rate limiting
+	Throttled    uint64
+	ThrottleWait time.Duration
+	RateLimited  uint64
+
+	// compression, the ratio is the uncompressed size over the compressed size
+	Compressed        uint64
+	UncompressedBytes uint64
+	CompressedBytes   uint64
+	CompressionRatio  float64
+	CompressionTime   time.Duration
+
+	// encryption
+	Encrypted uint64
+
+	// signing
+	Signed uint64
+
+	// claim check
+	ClaimChecked      uint64
+	ClaimCheckedBytes uint64
+}
+
 /*
 	Object interfaces
 */
@@ -58,13 +383,24 @@ type Publisher interface {
 	Init() error
 	Retry() error
 	SendMessage([]byte) error
+	SendMessageWithKey(string, []byte) error
+	SendMessageWithHeaders(amqp.Table, []byte) error
+	SendMessageWithProperties(Message) error
+	SendMessageAsync([]byte) (Confirmation, error)
+	SendMessageWithPropertiesAsync(Message) (Confirmation, error)
+	SendMessages([]Message) ([]PublishResult, error)
+	GetStats() PublisherStats
+	SetRateLimit(limit RateLimit) error
 	Teardown() error
 }
 
 type Subscriber interface {
 	GetName() string
+	GetStats() SubscriberStats
 	Init() error
 	Retry() error
+	SetPrefetch(count, size int) error
+	SetRateLimit(limit RateLimit) error
 	Teardown() error
 }
 
@@ -76,6 +412,120 @@ type RabbitMessageHandler interface {
 	ProcessMessage(byData []byte) error
 }
 
+/*
+	Richer alternative to RabbitMessageHandler which receives the full Delivery
+	including headers, routing information and message properties
+*/
+type RabbitDeliveryHandler interface {
+	ProcessDelivery(delivery *Delivery) error
+}
+
+/*
+	Handler which decides how each delivery is acknowledged. Returning
+	AckOutcomeDefault applies the same mapping used for the other handlers: nil
+	acks, an error applies the subscriber ErrorOutcome.
+*/
+type RabbitAckHandler interface {
+	HandleDelivery(delivery *Delivery) (AckOutcome, error)
+}
+
+/*
+	Handler which receives up to BatchSize deliveries, or whatever arrived within
+	BatchTimeout. Returning no outcomes settles the whole batch from the error
+	alone, otherwise there must be one outcome per delivery.
+*/
+type RabbitBatchHandler interface {
+	ProcessBatch(deliveries []*Delivery) ([]AckOutcome, error)
+}
+
+/*
+	Persists the last processed offset of a stream subscriber so that a restarted
+	consumer resumes where it stopped
+*/
+type OffsetStore interface {
+	LoadOffset(name string) (int64, bool, error)
+	StoreOffset(name string, offset int64) error
+}
+
+/*
+	Called when the broker returns a mandatory message it could not route
+*/
+type ReturnHandler interface {
+	ProcessReturn(ret *Return)
+}
+
+/*
+	Told when the circuit breaker of a Subscriber changes state. While the breaker
+	is open the Subscriber does not consume.
+*/
+type CircuitBreakerHandler interface {
+	CircuitStateChanged(name string, from CircuitState, to CircuitState)
+}
+
+/*
+	Turns values into message bodies and back. ContentType is set on published
+	messages and used to pick the codec for received ones.
+*/
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+/*
+	Supplies the keys used to wrap the per message data keys. New messages use
+	the current key, older ones are decrypted with whichever key id they carry.
+*/
+type KeyProvider interface {
+	CurrentKey() (id string, key []byte, err error)
+	GetKey(id string) ([]byte, error)
+}
+
+/*
+	Signs the canonical form of outgoing messages. The algorithm and key id are
+	sent along so the subscriber knows which key to verify with.
+*/
+type Signer interface {
+	Algorithm() string
+	KeyId() string
+	Sign(data []byte) ([]byte, error)
+}
+
+/*
+	Checks the signature of incoming messages against the named key
+*/
+type Verifier interface {
+	Verify(algorithm string, keyId string, data []byte, signature []byte) error
+}
+
+/*
+	Holds message bodies too large to send through the broker. Only a reference
+	travels with the message and the subscriber fetches the body by it.
+*/
+type BlobStore interface {
+	Put(key string, data []byte) error
+	Get(key string) ([]byte, error)
+	Delete(key string) error
+}
+
+/*
+	Told about deliveries that were dead lettered without reaching the handler
+	because they could not be read, such as ones that fail to decrypt
+*/
+type UnreadableHandler interface {
+	ProcessUnreadable(delivery *Delivery, err error)
+}
+
+/*
+	Publishes to Publishers by name inside a transaction. Nothing is delivered
+	until the transaction commits.
+*/
+type PublishTx interface {
+	SendMessage(name string, data []byte) error
+	SendMessageWithKey(name string, key string, data []byte) error
+	SendMessageWithProperties(name string, msg Message) error
+}
+
 /*
 	Interface to the Rabbit Manager which keeps track of all Publishers and Subscribers
 	for a given instance
@@ -88,6 +538,7 @@ type Manager interface {
 	GetPublisherByName(name string) (*Publisher, error)
 	GetSubscriberByName(name string) (*Subscriber, error)
 	PublishMessageByName(name string, data []byte) error
+	PublishTx(fn func(tx PublishTx) error) error
 	DeletePublisher(name string) error
 	DeleteSubscriber(name string) error
 	Teardown() error
@@ -5,6 +5,12 @@ package manager
 
 import (
 	"errors"
+	"time"
+)
+
+const (
+	// DefaultRecoverInterval how long to wait between recovery attempts
+	DefaultRecoverInterval time.Duration = 5 * time.Second
 )
 
 var (
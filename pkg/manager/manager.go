@@ -27,6 +27,12 @@ func New(options ManagerOptions) (*Manager, error) {
 		publishers:  make(map[string]*publisher.Publisher),
 		connection:  conn,
 	}
+
+	if options.AutoRecover {
+		rabbit.stopChan = make(chan struct{})
+		go rabbit.watchConnection(conn)
+	}
+
 	return rabbit, nil
 }
 
@@ -37,8 +43,23 @@ func (m *Manager) Retry() error {
 	var retErr error
 	retErr = nil
 
+	m.recoverMu.Lock()
+	defer m.recoverMu.Unlock()
+
+	// a dead connection has to come back before any channel can
+	err := m.redial()
+	if err != nil {
+		klog.V(1).Infof("redial failed. Err: %v\n", err)
+		klog.V(6).Infof("Manager.Retry LEAVE\n")
+		return err
+	}
+
 	for _, publisher := range m.publishers {
-		err := publisher.Retry()
+		if publisher.IsClosed() {
+			err = m.recoverPublisher(publisher)
+		} else {
+			err = publisher.Retry()
+		}
 		if err != nil {
 			klog.V(1).Infof("publisher.Retry %s not found\n", publisher.GetName())
 			retErr = err
@@ -46,7 +67,11 @@ func (m *Manager) Retry() error {
 	}
 
 	for _, subscriber := range m.subscribers {
-		err := subscriber.Retry()
+		if subscriber.IsClosed() {
+			err = m.recoverSubscriber(subscriber)
+		} else {
+			err = subscriber.Retry()
+		}
 		if err != nil {
 			klog.V(1).Infof("subscriber.Retry %s not found\n", subscriber.GetName())
 			retErr = err
@@ -78,10 +103,17 @@ func (m *Manager) CreatePublisher(options interfaces.PublisherOptions) (*interfa
 		options.DeleteWarnings = true
 	}
 	publisherOptions := publisher.PublisherOptions{
-		&options,
-		ch,
+		PublisherOptions: &options,
+		Channel:          ch,
+		OpenChannel:      m.openChannel,
+	}
+	publisher, err := publisher.New(publisherOptions)
+	if err != nil {
+		klog.V(1).Infof("New() failed. Err: %v\n", err)
+		klog.V(6).Infof("Manager.CreatePublisher LEAVE\n")
+		ch.Close()
+		return nil, err
 	}
-	publisher := publisher.New(publisherOptions)
 
 	err = publisher.Init()
 	if err != nil {
@@ -94,6 +126,8 @@ func (m *Manager) CreatePublisher(options interfaces.PublisherOptions) (*interfa
 	m.publishers[options.Name] = publisher
 	m.mu.Unlock()
 
+	m.watchPublisher(publisher, ch)
+
 	var pubInterface interfaces.Publisher
 	pubInterface = publisher
 
@@ -118,15 +152,23 @@ func (m *Manager) CreateSubscriber(options interfaces.SubscriberOptions) (*inter
 		options.DeleteWarnings = true
 	}
 	subscriberOptions := subscriber.SubscriberOptions{
-		&options,
-		ch,
+		SubscriberOptions: &options,
+		Channel:           ch,
+	}
+	subscriber, err := subscriber.New(subscriberOptions)
+	if err != nil {
+		klog.V(1).Infof("New() failed. Err: %v\n", err)
+		klog.V(6).Infof("Manager.CreateSubscriber LEAVE\n")
+		ch.Close()
+		return nil, err
 	}
-	subscriber := subscriber.New(subscriberOptions)
 
 	m.mu.Lock()
 	m.subscribers[options.Name] = subscriber
 	m.mu.Unlock()
 
+	m.watchSubscriber(subscriber, ch)
+
 	var subInterface interfaces.Subscriber
 	subInterface = subscriber
 
@@ -333,6 +375,13 @@ func (m *Manager) Teardown() error {
 	var retErr error
 	retErr = nil
 
+	// stop recovering before the connection goes away on purpose
+	m.stopOnce.Do(func() {
+		if stopChan := m.stopSignal(); stopChan != nil {
+			close(stopChan)
+		}
+	})
+
 	// clean up subs and pubs
 	for _, subscriber := range m.subscribers {
 		err := subscriber.Teardown()
@@ -355,6 +404,13 @@ func (m *Manager) Teardown() error {
 	m.mu.Unlock()
 
 	// clean up rabbitmq
+	m.txMu.Lock()
+	if m.txChannel != nil {
+		m.txChannel.Close()
+		m.txChannel = nil
+	}
+	m.txMu.Unlock()
+
 	if m.connection != nil {
 		m.connection.Close()
 		m.connection = nil
@@ -0,0 +1,246 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package manager
+
+import (
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	publisher "github.com/dvonthenen/rabbitmq-manager/pkg/publisher"
+	subscriber "github.com/dvonthenen/rabbitmq-manager/pkg/subscriber"
+)
+
+/*
+	Waits for the connection to drop and retries until everything is recovered.
+	A graceful close from Teardown ends the watch.
+*/
+func (m *Manager) watchConnection(conn *amqp.Connection) {
+	stopChan := m.stopSignal()
+
+	closeChan := conn.NotifyClose(make(chan *amqp.Error, 1))
+	select {
+	case err, ok := <-closeChan:
+		if !ok || err == nil {
+			klog.V(5).Infof("Exiting Manager Watch Loop\n")
+			return
+		}
+		klog.V(1).Infof("Connection closed. Err: %v\n", err)
+	case <-stopChan:
+		klog.V(5).Infof("Exiting Manager Watch Loop\n")
+		return
+	}
+
+	interval := m.recoverInterval()
+	for {
+		select {
+		case <-time.After(interval):
+		case <-stopChan:
+			klog.V(5).Infof("Exiting Manager Watch Loop\n")
+			return
+		}
+
+		err := m.Retry()
+		if err == nil {
+			klog.V(3).Infof("Manager recovered\n")
+			return
+		}
+		klog.V(1).Infof("Manager recovery failed. Err: %v\n", err)
+	}
+}
+
+/*
+	Waits for a single channel to be closed by the broker, such as after a
+	channel exception, and recovers whatever used it. A dead connection is left
+	to watchConnection and a graceful close ends the watch.
+*/
+func (m *Manager) watchChannel(ch *amqp.Channel, name string, recoverFn func() error) {
+	stopChan := m.stopSignal()
+
+	closeChan := ch.NotifyClose(make(chan *amqp.Error, 1))
+	select {
+	case err, ok := <-closeChan:
+		if !ok || err == nil {
+			klog.V(5).Infof("Exiting Channel Watch Loop for %s\n", name)
+			return
+		}
+		klog.V(1).Infof("Channel for %s closed. Err: %v\n", name, err)
+	case <-stopChan:
+		klog.V(5).Infof("Exiting Channel Watch Loop for %s\n", name)
+		return
+	}
+
+	interval := m.recoverInterval()
+	for {
+		select {
+		case <-time.After(interval):
+		case <-stopChan:
+			klog.V(5).Infof("Exiting Channel Watch Loop for %s\n", name)
+			return
+		}
+
+		if m.connectionClosed() {
+			klog.V(5).Infof("Connection closed, leaving %s to the connection recovery\n", name)
+			return
+		}
+
+		err := recoverFn()
+		if err == nil {
+			klog.V(3).Infof("Channel for %s recovered\n", name)
+			return
+		}
+		klog.V(1).Infof("Channel recovery for %s failed. Err: %v\n", name, err)
+	}
+}
+
+func (m *Manager) watchPublisher(publisher *publisher.Publisher, ch *amqp.Channel) {
+	if m.stopSignal() == nil {
+		return
+	}
+
+	go m.watchChannel(ch, publisher.GetName(), func() error {
+		m.recoverMu.Lock()
+		defer m.recoverMu.Unlock()
+
+		// deleted or already recovered in the meantime
+		m.mu.Lock()
+		current := m.publishers[publisher.GetName()]
+		m.mu.Unlock()
+		if current != publisher {
+			return nil
+		}
+		// a replay that failed after the channel was swapped left the spool behind
+		if !publisher.IsClosed() {
+			return publisher.ReplaySpool()
+		}
+
+		return m.recoverPublisher(publisher)
+	})
+}
+
+func (m *Manager) watchSubscriber(subscriber *subscriber.Subscriber, ch *amqp.Channel) {
+	if m.stopSignal() == nil {
+		return
+	}
+
+	go m.watchChannel(ch, subscriber.GetName(), func() error {
+		m.recoverMu.Lock()
+		defer m.recoverMu.Unlock()
+
+		// deleted or already recovered in the meantime
+		m.mu.Lock()
+		current := m.subscribers[subscriber.GetName()]
+		m.mu.Unlock()
+		if current != subscriber || !subscriber.IsClosed() {
+			return nil
+		}
+
+		return m.recoverSubscriber(subscriber)
+	})
+}
+
+/*
+	Channel closed by Teardown to stop every watch, nil without AutoRecover
+*/
+func (m *Manager) stopSignal() chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.stopChan
+}
+
+func (m *Manager) recoverInterval() time.Duration {
+	if m.options.RecoverInterval == 0 {
+		return DefaultRecoverInterval
+	}
+	return m.options.RecoverInterval
+}
+
+func (m *Manager) connectionClosed() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.connection == nil || m.connection.IsClosed()
+}
+
+/*
+	Opens a channel on the current connection, for the publisher channel pools
+*/
+func (m *Manager) openChannel() (*amqp.Channel, error) {
+	m.mu.Lock()
+	conn := m.connection
+	m.mu.Unlock()
+
+	if conn == nil {
+		return nil, amqp.ErrClosed
+	}
+	return conn.Channel()
+}
+
+/*
+	Dials a new connection if the current one is closed
+*/
+func (m *Manager) redial() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.connection != nil && !m.connection.IsClosed() {
+		return nil
+	}
+
+	klog.V(3).Infof("Redialing RabbitMQ\n")
+	conn, err := amqp.Dial(m.options.RabbitURI)
+	if err != nil {
+		klog.V(1).Infof("amqp.Dial failed. Err: %v\n", err)
+		return err
+	}
+	m.connection = conn
+
+	if m.stopChan != nil {
+		go m.watchConnection(conn)
+	}
+
+	return nil
+}
+
+func (m *Manager) recoverPublisher(publisher *publisher.Publisher) error {
+	ch, err := m.connection.Channel()
+	if err != nil {
+		klog.V(1).Infof("Channel() failed. Err: %v\n", err)
+		return err
+	}
+
+	err = publisher.Recover(ch)
+	if err != nil {
+		klog.V(1).Infof("publisher.Recover %s failed. Err: %v\n", publisher.GetName(), err)
+		// the publisher keeps the channel when only the replay failed
+		if !publisher.IsClosed() {
+			m.watchPublisher(publisher, ch)
+		}
+		return err
+	}
+
+	m.watchPublisher(publisher, ch)
+
+	return nil
+}
+
+func (m *Manager) recoverSubscriber(subscriber *subscriber.Subscriber) error {
+	ch, err := m.connection.Channel()
+	if err != nil {
+		klog.V(1).Infof("Channel() failed. Err: %v\n", err)
+		return err
+	}
+
+	err = subscriber.Recover(ch)
+	if err != nil {
+		klog.V(1).Infof("subscriber.Recover %s failed. Err: %v\n", subscriber.GetName(), err)
+		return err
+	}
+
+	m.watchSubscriber(subscriber, ch)
+
+	return nil
+}
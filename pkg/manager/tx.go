@@ -0,0 +1,128 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package manager
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Publishes everything sent through tx in a single AMQP transaction. The
+	transaction commits when fn returns nil and rolls back when it returns an
+	error or panics. Transactions run on a dedicated channel since a channel in
+	confirm mode cannot be switched to transaction mode, and are serialized.
+*/
+func (m *Manager) PublishTx(fn func(tx interfaces.PublishTx) error) error {
+	klog.V(6).Infof("Manager.PublishTx ENTER\n")
+
+	m.txMu.Lock()
+	defer m.txMu.Unlock()
+
+	ch, err := m.transactionChannel()
+	if err != nil {
+		klog.V(1).Infof("transactionChannel failed. Err: %v\n", err)
+		klog.V(6).Infof("Manager.PublishTx LEAVE\n")
+		return err
+	}
+
+	tx := &publishTx{
+		manager: m,
+		channel: ch,
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			klog.V(1).Infof("PublishTx callback panicked: %v\n", r)
+			rollback(ch)
+			klog.V(6).Infof("Manager.PublishTx LEAVE\n")
+			panic(r)
+		}
+	}()
+
+	err = fn(tx)
+	if err != nil {
+		klog.V(1).Infof("PublishTx callback failed. Err: %v\n", err)
+		rollback(ch)
+		klog.V(6).Infof("Manager.PublishTx LEAVE\n")
+		return err
+	}
+
+	err = ch.TxCommit()
+	if err != nil {
+		klog.V(1).Infof("TxCommit failed. Err: %v\n", err)
+		klog.V(6).Infof("Manager.PublishTx LEAVE\n")
+		return err
+	}
+
+	klog.V(4).Infof("Manager.PublishTx Succeeded\n")
+	klog.V(6).Infof("Manager.PublishTx LEAVE\n")
+
+	return nil
+}
+
+/*
+	Returns the channel transactions run on, opening it and switching it to
+	transaction mode when needed. Caller must hold txMu.
+*/
+func (m *Manager) transactionChannel() (*amqp.Channel, error) {
+	if m.txChannel != nil && !m.txChannel.IsClosed() {
+		return m.txChannel, nil
+	}
+
+	m.mu.Lock()
+	conn := m.connection
+	m.mu.Unlock()
+
+	ch, err := conn.Channel()
+	if err != nil {
+		klog.V(1).Infof("Channel() failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	err = ch.Tx()
+	if err != nil {
+		klog.V(1).Infof("Tx failed. Err: %v\n", err)
+		ch.Close()
+		return nil, err
+	}
+
+	m.txChannel = ch
+	return ch, nil
+}
+
+func rollback(ch *amqp.Channel) {
+	err := ch.TxRollback()
+	if err != nil {
+		klog.V(1).Infof("TxRollback failed. Err: %v\n", err)
+	}
+}
+
+func (t *publishTx) SendMessage(name string, data []byte) error {
+	return t.SendMessageWithProperties(name, interfaces.Message{
+		Body: data,
+	})
+}
+
+func (t *publishTx) SendMessageWithKey(name string, key string, data []byte) error {
+	return t.SendMessageWithProperties(name, interfaces.Message{
+		RoutingKey: key,
+		Body:       data,
+	})
+}
+
+func (t *publishTx) SendMessageWithProperties(name string, msg interfaces.Message) error {
+	t.manager.mu.Lock()
+	publisher := t.manager.publishers[name]
+	t.manager.mu.Unlock()
+
+	if publisher == nil {
+		klog.V(1).Infof("Publisher %s not found\n", name)
+		return ErrPublisherNotFound
+	}
+
+	return publisher.PublishOn(t.channel, msg)
+}
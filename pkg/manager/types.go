@@ -30,4 +30,21 @@ type Manager struct {
 
 	// rabbitmq
 	connection *amqp.Connection
+
+	// recovery
+	stopChan  chan struct{}
+	stopOnce  sync.Once
+	recoverMu sync.Mutex
+
+	// transactions
+	txChannel *amqp.Channel
+	txMu      sync.Mutex
+}
+
+/*
+	Transaction handed to the PublishTx callback
+*/
+type publishTx struct {
+	manager *Manager
+	channel *amqp.Channel
 }
@@ -0,0 +1,23 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package offset
+
+import (
+	"errors"
+)
+
+const (
+	// DefaultDirectory where the file store keeps offsets when none is given
+	DefaultDirectory string = ".rabbitmq-manager/offsets"
+
+	fileExtension string = ".offset"
+)
+
+var (
+	// ErrInvalidOffsetName the offset name is empty
+	ErrInvalidOffsetName = errors.New("the offset name is empty")
+
+	// ErrCorruptOffset the stored offset could not be parsed
+	ErrCorruptOffset = errors.New("the stored offset could not be parsed")
+)
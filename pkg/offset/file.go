@@ -0,0 +1,80 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package offset
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	klog "k8s.io/klog/v2"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+)
+
+func NewFileStore(directory string) (*FileStore, error) {
+	if directory == "" {
+		directory = DefaultDirectory
+	}
+
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		klog.V(1).Infof("MkdirAll %s failed. Err: %v\n", directory, err)
+		return nil, err
+	}
+
+	store := &FileStore{
+		directory: directory,
+	}
+	return store, nil
+}
+
+func (f *FileStore) filename(name string) string {
+	return filepath.Join(f.directory, url.PathEscape(name)+fileExtension)
+}
+
+func (f *FileStore) LoadOffset(name string) (int64, bool, error) {
+	if name == "" {
+		return 0, false, ErrInvalidOffsetName
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, err := os.ReadFile(f.filename(name))
+	if os.IsNotExist(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		klog.V(1).Infof("ReadFile %s failed. Err: %v\n", name, err)
+		return 0, false, err
+	}
+
+	offset, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	if err != nil {
+		klog.V(1).Infof("ParseInt %s failed. Err: %v\n", name, err)
+		return 0, false, ErrCorruptOffset
+	}
+
+	return offset, true, nil
+}
+
+func (f *FileStore) StoreOffset(name string, offset int64) error {
+	if name == "" {
+		return ErrInvalidOffsetName
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	err := common.WriteFileAtomic(f.filename(name), []byte(strconv.FormatInt(offset, 10)), 0644)
+	if err != nil {
+		klog.V(1).Infof("WriteFileAtomic %s failed. Err: %v\n", name, err)
+		return err
+	}
+
+	return nil
+}
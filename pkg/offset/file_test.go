@@ -0,0 +1,110 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package offset
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestStoreLoad(t *testing.T) {
+	tests := []struct {
+		name   string
+		offset int64
+	}{
+		{"plain", 42},
+		{"zero", 0},
+		{"large", 1 << 62},
+		{"name/with/slashes", 7},
+		{"../escape", 9},
+	}
+
+	dir := t.TempDir()
+	store, err := NewFileStore(dir)
+	if err != nil {
+		t.Fatalf("NewFileStore failed. Err: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, found, err := store.LoadOffset(tt.name)
+			if err != nil || found {
+				t.Fatalf("LoadOffset before store found %v. Err: %v", found, err)
+			}
+
+			for _, offset := range []int64{tt.offset + 1, tt.offset} {
+				err = store.StoreOffset(tt.name, offset)
+				if err != nil {
+					t.Fatalf("StoreOffset failed. Err: %v", err)
+				}
+			}
+
+			offset, found, err := store.LoadOffset(tt.name)
+			if err != nil || !found || offset != tt.offset {
+				t.Fatalf("LoadOffset() = %d, %v, want %d. Err: %v", offset, found, tt.offset, err)
+			}
+		})
+	}
+
+	// one file per name, all inside the directory and no temporary files left
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(tests) {
+		t.Fatalf("directory holds %d files, want %d", len(entries), len(tests))
+	}
+	for _, entry := range entries {
+		if filepath.Ext(entry.Name()) != fileExtension {
+			t.Fatalf("unexpected file %s", entry.Name())
+		}
+	}
+}
+
+func TestLoadCorrupt(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		err  error
+	}{
+		{"trailing newline", "12\n", nil},
+		{"empty", "", ErrCorruptOffset},
+		{"garbage", "twelve", ErrCorruptOffset},
+		{"torn", "12 3", ErrCorruptOffset},
+	}
+
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed. Err: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := os.WriteFile(store.filename(tt.name), []byte(tt.data), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, _, err = store.LoadOffset(tt.name)
+			if err != tt.err {
+				t.Fatalf("LoadOffset() err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestInvalidName(t *testing.T) {
+	store, err := NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore failed. Err: %v", err)
+	}
+
+	if _, _, err := store.LoadOffset(""); err != ErrInvalidOffsetName {
+		t.Fatalf("LoadOffset() err = %v, want %v", err, ErrInvalidOffsetName)
+	}
+	if err := store.StoreOffset("", 1); err != ErrInvalidOffsetName {
+		t.Fatalf("StoreOffset() err = %v, want %v", err, ErrInvalidOffsetName)
+	}
+}
@@ -0,0 +1,16 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package offset
+
+import (
+	"sync"
+)
+
+/*
+	Local file backed offset store. Each offset name is kept in its own file.
+*/
+type FileStore struct {
+	directory string
+	mu        sync.Mutex
+}
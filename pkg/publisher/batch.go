@@ -0,0 +1,124 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"context"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	A prepared message and its place in the batch
+*/
+type batchMessage struct {
+	index      int
+	key        string
+	publishing amqp.Publishing
+}
+
+/*
+	Publishes a batch of messages back to back and, in confirm mode, waits for all
+	of their confirmations together instead of one round trip per message. With
+	BatchChannels the batch is spread over the publisher channel and the pooled
+	ones, so messages of the same batch may be delivered out of order. There is
+	one result per message in the same order, a spooled message has ErrSpooled.
+	The error is the first failure in the batch, if any.
+*/
+func (p *Publisher) SendMessages(msgs []interfaces.Message) ([]interfaces.PublishResult, error) {
+	klog.V(6).Infof("Publisher.SendMessages ENTER\n")
+	klog.V(3).Infof("Publishing %d messages to: %s\n", len(msgs), p.GetName())
+
+	results := make([]interfaces.PublishResult, len(msgs))
+	pendings := make([]*confirmation, len(msgs))
+
+	batch := make([]batchMessage, 0, len(msgs))
+	for i := range msgs {
+		key, publishing, err := p.prepare(&msgs[i])
+		if err != nil {
+			klog.V(1).Infof("prepare failed. Err: %v\n", err)
+			results[i].Err = err
+			continue
+		}
+		results[i].MessageId = publishing.MessageId
+		batch = append(batch, batchMessage{i, key, publishing})
+	}
+
+	// the publisher channel takes the first share, each pooled channel another
+	channels := p.batchChannels()
+	shares := len(channels) + 1
+
+	var wg sync.WaitGroup
+	for share := 0; share < shares; share++ {
+		wg.Add(1)
+		go func(share int) {
+			defer wg.Done()
+
+			for j := share; j < len(batch); j += shares {
+				msg := batch[j]
+
+				var pending *confirmation
+				var isSpooled bool
+				var err error
+				if share == 0 {
+					pending, isSpooled, err = p.sendOrSpool(msg.key, msg.publishing)
+				} else {
+					pending, isSpooled, err = p.sendPooled(channels[share-1], msg.key, msg.publishing)
+				}
+				if err != nil && !isSpooled {
+					klog.V(1).Infof("send failed. Err: %v\n", err)
+				}
+				results[msg.index].Err = err
+				pendings[msg.index] = pending
+			}
+		}(share)
+	}
+	wg.Wait()
+
+	// the timeout covers the whole batch since the confirmations arrive together
+	ctx, cancel := context.WithTimeout(context.Background(), p.confirmTimeout)
+	defer cancel()
+
+	for i, pending := range pendings {
+		if pending == nil {
+			continue
+		}
+		err := pending.Wait(ctx)
+		if err != nil {
+			klog.V(1).Infof("Wait for confirmation %d failed. Err: %v\n", pending.DeliveryTag(), err)
+			results[i].Err = err
+		}
+	}
+
+	var retErr error
+	failed := 0
+	spooled := 0
+	for _, result := range results {
+		if result.Err == ErrSpooled {
+			spooled++
+		}
+		if result.Err != nil {
+			if retErr == nil {
+				retErr = result.Err
+			}
+			failed++
+		}
+	}
+
+	if spooled > 0 {
+		klog.V(3).Infof("Publisher.SendMessages %s spooled %d messages\n", p.GetName(), spooled)
+	}
+	if retErr == nil {
+		klog.V(4).Infof("Publisher.SendMessages %s succeeded (%d messages)\n", p.GetName(), len(msgs))
+	} else {
+		klog.V(1).Infof("Publisher.SendMessages %s failed %d of %d messages\n", p.GetName(), failed, len(msgs))
+	}
+	klog.V(6).Infof("Publisher.SendMessages LEAVE\n")
+
+	return results, retErr
+}
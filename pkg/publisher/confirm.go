@@ -0,0 +1,286 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"context"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Publisher side future for a single confirmation
+*/
+type confirmation struct {
+	deliveryTag uint64
+	messageId   string
+	returnId    int64
+	returned    error
+	doneChan    chan struct{}
+	err         error
+	once        sync.Once
+	mu          sync.Mutex
+
+	// ledger
+	channel    *amqp.Channel
+	sequence   uint64
+	key        string
+	publishing amqp.Publishing
+}
+
+func newConfirmation(channel *amqp.Channel, sequence uint64, key string, publishing amqp.Publishing) *confirmation {
+	returnId, _ := publishing.Headers[common.HeaderPublishSequence].(int64)
+
+	return &confirmation{
+		deliveryTag: channel.GetNextPublishSeqNo(),
+		messageId:   publishing.MessageId,
+		returnId:    returnId,
+		doneChan:    make(chan struct{}),
+		channel:     channel,
+		sequence:    sequence,
+		key:         key,
+		publishing:  publishing,
+	}
+}
+
+/*
+	Delivery tags restart with every channel
+*/
+type ledgerKey struct {
+	channel     *amqp.Channel
+	deliveryTag uint64
+}
+
+func (c *confirmation) DeliveryTag() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.deliveryTag
+}
+
+/*
+	Moves the confirmation onto the channel it is republished on
+*/
+func (c *confirmation) rebind(channel *amqp.Channel, deliveryTag uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.channel = channel
+	c.deliveryTag = deliveryTag
+}
+
+func (c *confirmation) Done() <-chan struct{} {
+	return c.doneChan
+}
+
+func (c *confirmation) Wait(ctx context.Context) error {
+	select {
+	case <-c.doneChan:
+		return c.err
+	case <-ctx.Done():
+		if ctx.Err() == context.DeadlineExceeded {
+			return ErrConfirmTimeout
+		}
+		return ctx.Err()
+	}
+}
+
+func (c *confirmation) resolve(err error) {
+	c.once.Do(func() {
+		c.err = err
+		close(c.doneChan)
+	})
+}
+
+/*
+	Puts the channel into confirm mode and/or listens for returned messages. Only
+	done once per channel.
+*/
+func (p *Publisher) enableNotifications() error {
+	if p.notifyChannel == p.channel {
+		return nil
+	}
+
+	err := p.listen(p.channel, p.options.Republish)
+	if err != nil {
+		return err
+	}
+	p.notifyChannel = p.channel
+
+	return nil
+}
+
+/*
+	Starts the notify loop of a channel. With keep set the pending confirmations
+	outlive the channel so they can be republished after a recovery.
+*/
+func (p *Publisher) listen(channel *amqp.Channel, keep bool) error {
+	if !p.options.ConfirmMode && !p.options.Mandatory {
+		return nil
+	}
+
+	var confirms chan amqp.Confirmation
+	if p.options.ConfirmMode {
+		klog.V(3).Infof("Confirm: %s\n", p.GetName())
+		err := channel.Confirm(false)
+		if err != nil {
+			klog.V(1).Infof("Confirm %s failed. Err: %v\n", p.GetName(), err)
+			return err
+		}
+		confirms = channel.NotifyPublish(make(chan amqp.Confirmation))
+	}
+
+	var returns chan amqp.Return
+	if p.options.Mandatory {
+		returns = channel.NotifyReturn(make(chan amqp.Return))
+	}
+
+	go p.notifyLoop(channel, confirms, returns, keep)
+
+	return nil
+}
+
+/*
+	Handles confirmations and returns until the channel closes. A single goroutine
+	keeps the broker ordering where a return always arrives before the confirmation
+	of the same message. Whatever is still pending afterwards fails, unless it is
+	kept for republishing after a recovery.
+*/
+func (p *Publisher) notifyLoop(channel *amqp.Channel, confirms <-chan amqp.Confirmation, returns <-chan amqp.Return, keep bool) {
+	for confirms != nil || returns != nil {
+		select {
+		case c, ok := <-confirms:
+			if !ok {
+				confirms = nil
+				continue
+			}
+			p.confirm(channel, c)
+		case r, ok := <-returns:
+			if !ok {
+				returns = nil
+				continue
+			}
+			p.handleReturn(&r)
+		}
+	}
+
+	if !keep {
+		p.failPending(channel, ErrChannelClosed)
+	}
+	klog.V(5).Infof("Exiting Publisher Notify Loop\n")
+}
+
+func (p *Publisher) confirm(channel *amqp.Channel, c amqp.Confirmation) {
+	key := ledgerKey{channel, c.DeliveryTag}
+
+	p.pendingMu.Lock()
+	pending, ok := p.pending[key]
+	delete(p.pending, key)
+	p.pendingMu.Unlock()
+
+	if !ok {
+		klog.V(1).Infof("Confirmation %d on %s has no pending publish\n", c.DeliveryTag, p.GetName())
+		return
+	}
+	p.releaseSlot()
+
+	switch {
+	case !c.Ack:
+		klog.V(1).Infof("Nack %d on %s\n", c.DeliveryTag, p.GetName())
+		pending.resolve(&NackError{
+			DeliveryTag: c.DeliveryTag,
+			MessageId:   pending.messageId,
+		})
+	case pending.returned != nil:
+		klog.V(1).Infof("Returned %d on %s\n", c.DeliveryTag, p.GetName())
+		pending.resolve(pending.returned)
+	default:
+		klog.V(5).Infof("Ack %d on %s\n", c.DeliveryTag, p.GetName())
+		pending.resolve(nil)
+	}
+}
+
+/*
+	Marks the pending confirmation of a returned message so that its ack fails,
+	and hands the message to the ReturnHandler. Returns carry no delivery tag so
+	they are matched on the sequence header set by send.
+*/
+func (p *Publisher) handleReturn(r *amqp.Return) {
+	klog.V(1).Infof("Message %s returned on %s. Code: %d, Reason: %s\n", r.MessageId, p.GetName(), r.ReplyCode, r.ReplyText)
+
+	returnId, ok := r.Headers[common.HeaderPublishSequence].(int64)
+	if ok {
+		p.pendingMu.Lock()
+		for _, pending := range p.pending {
+			if pending.returnId == returnId {
+				pending.returned = &ReturnError{
+					ReplyCode: r.ReplyCode,
+					ReplyText: r.ReplyText,
+					MessageId: r.MessageId,
+				}
+				break
+			}
+		}
+		p.pendingMu.Unlock()
+
+		r.Headers = copyHeaders(r.Headers)
+		delete(r.Headers, common.HeaderPublishSequence)
+	}
+
+	if p.options.ReturnHandler == nil {
+		return
+	}
+
+	ret := &interfaces.Return{
+		ReplyCode: r.ReplyCode,
+		ReplyText: r.ReplyText,
+		Exchange:  r.Exchange,
+		Message: interfaces.Message{
+			RoutingKey:      r.RoutingKey,
+			Headers:         r.Headers,
+			ContentType:     r.ContentType,
+			ContentEncoding: r.ContentEncoding,
+			DeliveryMode:    r.DeliveryMode,
+			Priority:        r.Priority,
+			CorrelationId:   r.CorrelationId,
+			ReplyTo:         r.ReplyTo,
+			Expiration:      r.Expiration,
+			MessageId:       r.MessageId,
+			Timestamp:       r.Timestamp,
+			Type:            r.Type,
+			UserId:          r.UserId,
+			AppId:           r.AppId,
+			Body:            r.Body,
+		},
+	}
+
+	// never block the connection reader on application code
+	go (*p.options.ReturnHandler).ProcessReturn(ret)
+}
+
+/*
+	Fails the pending confirmations of a channel, or of every channel when nil
+*/
+func (p *Publisher) failPending(channel *amqp.Channel, err error) {
+	failed := make([]*confirmation, 0)
+
+	p.pendingMu.Lock()
+	for key, pending := range p.pending {
+		if channel == nil || key.channel == channel {
+			failed = append(failed, pending)
+			delete(p.pending, key)
+		}
+	}
+	p.pendingMu.Unlock()
+
+	for _, pending := range failed {
+		p.releaseSlot()
+		pending.resolve(err)
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	signing "github.com/dvonthenen/rabbitmq-manager/pkg/signing"
+)
+
+func newPending(p *Publisher, deliveryTag uint64, returnId int64, messageId string) *confirmation {
+	pending := &confirmation{
+		deliveryTag: deliveryTag,
+		messageId:   messageId,
+		returnId:    returnId,
+		doneChan:    make(chan struct{}),
+	}
+	p.pending[ledgerKey{nil, deliveryTag}] = pending
+	return pending
+}
+
+func TestReturnCorrelation(t *testing.T) {
+	tests := []struct {
+		name      string
+		headers   amqp.Table
+		messageId string
+		returned  []bool
+	}{
+		{"matched on sequence", amqp.Table{common.HeaderPublishSequence: int64(2)}, "b", []bool{false, true, false}},
+		{"without message ids", amqp.Table{common.HeaderPublishSequence: int64(3)}, "", []bool{false, false, true}},
+		{"shared message id", amqp.Table{common.HeaderPublishSequence: int64(1)}, "same", []bool{true, false, false}},
+		{"no sequence header", amqp.Table{}, "a", []bool{false, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Publisher{
+				options: PublisherOptions{PublisherOptions: &interfaces.PublisherOptions{Name: "test"}},
+				pending: make(map[ledgerKey]*confirmation),
+			}
+
+			ids := []string{"a", "b", ""}
+			if tt.messageId == "same" {
+				ids = []string{"same", "same", "same"}
+			}
+			pendings := make([]*confirmation, 0)
+			for i, id := range ids {
+				pendings = append(pendings, newPending(p, uint64(i+1), int64(i+1), id))
+			}
+
+			r := &amqp.Return{ReplyCode: 312, ReplyText: "NO_ROUTE", MessageId: tt.messageId, Headers: tt.headers}
+			p.handleReturn(r)
+
+			if _, ok := r.Headers[common.HeaderPublishSequence]; ok {
+				t.Fatalf("sequence header not stripped from the return")
+			}
+
+			for i, pending := range pendings {
+				p.confirm(nil, amqp.Confirmation{DeliveryTag: pending.deliveryTag, Ack: true})
+
+				err := pending.Wait(context.Background())
+				if tt.returned[i] != errors.Is(err, ErrPublishReturned) {
+					t.Fatalf("publish %d err = %v, returned %v", i+1, err, tt.returned[i])
+				}
+			}
+		})
+	}
+}
+
+func TestReturnSequenceIsSigned(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	signer, err := signing.NewHMACSigner("k1", key)
+	if err != nil {
+		t.Fatalf("NewHMACSigner failed. Err: %v", err)
+	}
+	var s interfaces.Signer = signer
+
+	tests := []struct {
+		name      string
+		confirm   bool
+		mandatory bool
+		sequenced bool
+	}{
+		{"confirmed mandatory", true, true, true},
+		{"confirmed", true, false, false},
+		{"mandatory", false, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := New(PublisherOptions{
+				PublisherOptions: &interfaces.PublisherOptions{
+					Name:        "test",
+					ConfirmMode: tt.confirm,
+					Mandatory:   tt.mandatory,
+					Signer:      &s,
+				},
+			})
+			if err != nil {
+				t.Fatalf("New failed. Err: %v", err)
+			}
+
+			ids := make(map[int64]bool)
+			for i := 0; i < 2; i++ {
+				_, publishing, err := p.prepare(&interfaces.Message{Body: []byte("body")})
+				if err != nil {
+					t.Fatalf("prepare failed. Err: %v", err)
+				}
+
+				id, found := publishing.Headers[common.HeaderPublishSequence].(int64)
+				if found != tt.sequenced {
+					t.Fatalf("sequence header present = %v, want %v", found, tt.sequenced)
+				}
+				signed, _ := publishing.Headers[signing.HeaderSignedHeaders].(string)
+				if strings.Contains(signed, common.HeaderPublishSequence) != tt.sequenced {
+					t.Fatalf("signed headers %q", signed)
+				}
+				if found && ids[id] {
+					t.Fatalf("sequence %d used twice", id)
+				}
+				ids[id] = true
+			}
+		})
+	}
+}
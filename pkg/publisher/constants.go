@@ -0,0 +1,107 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+const (
+	// DefaultContentType used when neither the message nor the publisher set one
+	DefaultContentType string = "text/plain"
+
+	// DefaultConfirmTimeout how long a blocking send waits for its confirmation
+	DefaultConfirmTimeout time.Duration = 5 * time.Second
+
+	// DefaultMaxUnconfirmed how many unconfirmed messages are kept for republishing
+	DefaultMaxUnconfirmed int = 1000
+
+	// HeaderRepublished counts how often a message was republished after a recovery
+	HeaderRepublished string = "x-republished"
+)
+
+var (
+	// ErrInvalidDeliveryMode the delivery mode must be transient or persistent
+	ErrInvalidDeliveryMode = errors.New("the delivery mode must be transient or persistent")
+
+	// ErrConfirmModeDisabled asynchronous sends require confirm mode
+	ErrConfirmModeDisabled = errors.New("asynchronous sends require confirm mode")
+
+	// ErrPublishNacked the broker nacked the message
+	ErrPublishNacked = errors.New("the broker nacked the message")
+
+	// ErrConfirmTimeout timed out waiting for the broker to confirm the message
+	ErrConfirmTimeout = errors.New("timed out waiting for the broker to confirm the message")
+
+	// ErrPublishReturned the broker returned the message as unroutable
+	ErrPublishReturned = errors.New("the broker returned the message as unroutable")
+
+	// ErrRepublishRequiresConfirm republishing requires confirm mode
+	ErrRepublishRequiresConfirm = errors.New("republishing requires confirm mode")
+
+	// ErrInvalidMaxUnconfirmed the maximum number of unconfirmed messages cannot be negative
+	ErrInvalidMaxUnconfirmed = errors.New("the maximum number of unconfirmed messages cannot be negative")
+
+	// ErrInvalidBatchChannels the number of batch channels cannot be negative
+	ErrInvalidBatchChannels = errors.New("the number of batch channels cannot be negative")
+
+	// ErrBatchChannelsRequireOpenChannel BatchChannels needs OpenChannel to open the pool
+	ErrBatchChannelsRequireOpenChannel = errors.New("BatchChannels needs OpenChannel to open the pool")
+
+	// ErrLedgerFull too many messages are waiting for a confirmation
+	ErrLedgerFull = errors.New("too many messages are waiting for a confirmation")
+
+	// ErrInvalidCompressionThreshold the compression threshold cannot be negative
+	ErrInvalidCompressionThreshold = errors.New("the compression threshold cannot be negative")
+
+	// ErrSignedHeadersRequireSigner SignedHeaders needs a Signer
+	ErrSignedHeadersRequireSigner = errors.New("SignedHeaders needs a Signer")
+
+	// ErrInvalidClaimCheckThreshold the claim check threshold cannot be negative
+	ErrInvalidClaimCheckThreshold = errors.New("the claim check threshold cannot be negative")
+
+	// ErrRateLimited the publisher is over its rate limit
+	ErrRateLimited = errors.New("the publisher is over its rate limit")
+
+	// ErrSpooled the message was spooled until the broker is reachable again
+	ErrSpooled = errors.New("the message was spooled until the broker is reachable again")
+
+	// ErrChannelClosed the channel closed before the message was confirmed
+	ErrChannelClosed = errors.New("the channel closed before the message was confirmed")
+)
+
+/*
+	Returned when the broker nacks a message, matches ErrPublishNacked
+*/
+type NackError struct {
+	DeliveryTag uint64
+	MessageId   string
+}
+
+func (e *NackError) Error() string {
+	return fmt.Sprintf("%v (tag: %d, id: %s)", ErrPublishNacked, e.DeliveryTag, e.MessageId)
+}
+
+func (e *NackError) Unwrap() error {
+	return ErrPublishNacked
+}
+
+/*
+	Returned when a mandatory message could not be routed, matches ErrPublishReturned
+*/
+type ReturnError struct {
+	ReplyCode uint16
+	ReplyText string
+	MessageId string
+}
+
+func (e *ReturnError) Error() string {
+	return fmt.Sprintf("%v (code: %d, reason: %s, id: %s)", ErrPublishReturned, e.ReplyCode, e.ReplyText, e.MessageId)
+}
+
+func (e *ReturnError) Unwrap() error {
+	return ErrPublishReturned
+}
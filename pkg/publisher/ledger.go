@@ -0,0 +1,102 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"context"
+	"sort"
+
+	klog "k8s.io/klog/v2"
+)
+
+/*
+	Reserves room in the unconfirmed ledger, waiting up to the confirm timeout
+	for a confirmation to free some up
+*/
+func (p *Publisher) acquireSlot() error {
+	if p.ledgerSlots == nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.confirmTimeout)
+	defer cancel()
+
+	select {
+	case p.ledgerSlots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ErrLedgerFull
+	}
+}
+
+func (p *Publisher) releaseSlot() {
+	if p.ledgerSlots == nil {
+		return
+	}
+	<-p.ledgerSlots
+}
+
+/*
+	Republishes every message that is unconfirmed on an earlier channel, in the
+	original publish order. Each one is re-registered under its new delivery tag
+	so existing Confirmations resolve once the broker confirms it. Caller must
+	hold publishMu.
+*/
+func (p *Publisher) republish() error {
+	p.pendingMu.Lock()
+	unconfirmed := make([]*confirmation, 0)
+	for key, pending := range p.pending {
+		if key.channel != p.channel {
+			unconfirmed = append(unconfirmed, pending)
+			delete(p.pending, key)
+		}
+	}
+	p.pendingMu.Unlock()
+
+	sort.Slice(unconfirmed, func(i, j int) bool {
+		return unconfirmed[i].sequence < unconfirmed[j].sequence
+	})
+
+	if len(unconfirmed) > 0 {
+		klog.V(3).Infof("Republishing %d unconfirmed messages on %s\n", len(unconfirmed), p.GetName())
+	}
+
+	for i, pending := range unconfirmed {
+		headers := copyHeaders(pending.publishing.Headers)
+		count, _ := headers[HeaderRepublished].(int32)
+		headers[HeaderRepublished] = count + 1
+		pending.publishing.Headers = headers
+		pending.returned = nil
+
+		previous := ledgerKey{pending.channel, pending.deliveryTag}
+		pending.rebind(p.channel, p.channel.GetNextPublishSeqNo())
+
+		p.pendingMu.Lock()
+		p.pending[ledgerKey{pending.channel, pending.deliveryTag}] = pending
+		p.pendingMu.Unlock()
+
+		err := p.channel.PublishWithContext(context.Background(),
+			p.options.Name,      // exchange
+			pending.key,         // routing key
+			p.options.Mandatory, // mandatory
+			false,               // immediate
+			pending.publishing,
+		)
+		if err != nil {
+			klog.V(1).Infof("Republish %s failed. Err: %v\n", pending.messageId, err)
+
+			// put the rest back under their old keys for the next recovery
+			p.pendingMu.Lock()
+			delete(p.pending, ledgerKey{pending.channel, pending.deliveryTag})
+			pending.rebind(previous.channel, previous.deliveryTag)
+			for _, remaining := range unconfirmed[i:] {
+				p.pending[ledgerKey{remaining.channel, remaining.deliveryTag}] = remaining
+			}
+			p.pendingMu.Unlock()
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,52 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+func TestFullLedgerDoesNotHoldPublishLock(t *testing.T) {
+	p, err := New(PublisherOptions{
+		PublisherOptions: &interfaces.PublisherOptions{
+			Name:           "test",
+			ConfirmMode:    true,
+			Republish:      true,
+			MaxUnconfirmed: 1,
+			ConfirmTimeout: 200 * time.Millisecond,
+		},
+	})
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+
+	err = p.acquireSlot()
+	if err != nil {
+		t.Fatalf("acquireSlot failed. Err: %v", err)
+	}
+
+	errChan := make(chan error, 1)
+	go func() {
+		_, err := p.send("", amqp.Publishing{})
+		errChan <- err
+	}()
+
+	// the send is waiting for a slot, IsClosed must not wait with it
+	time.Sleep(20 * time.Millisecond)
+	start := time.Now()
+	p.IsClosed()
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Fatalf("IsClosed() took %v while the ledger was full", elapsed)
+	}
+
+	err = <-errChan
+	if err != ErrLedgerFull {
+		t.Fatalf("send() err = %v, want ErrLedgerFull", err)
+	}
+}
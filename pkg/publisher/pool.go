@@ -0,0 +1,112 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"errors"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	A channel SendMessages publishes on besides the publisher channel
+*/
+type pooledChannel struct {
+	channel *amqp.Channel
+	mu      sync.Mutex
+}
+
+/*
+	Returns the pooled channels, opening any that are missing or closed. The pool
+	is only used while the publisher channel is open and nothing is spooled,
+	otherwise a batch goes through the publisher channel so the spool keeps the
+	publish order.
+*/
+func (p *Publisher) batchChannels() []*pooledChannel {
+	if p.options.BatchChannels == 0 || p.IsClosed() || p.HasSpooled() {
+		return nil
+	}
+
+	p.poolMu.Lock()
+	defer p.poolMu.Unlock()
+
+	open := make([]*pooledChannel, 0, p.options.BatchChannels)
+	for _, pc := range p.pool {
+		if !pc.channel.IsClosed() {
+			open = append(open, pc)
+		}
+	}
+	p.pool = open
+
+	for len(p.pool) < p.options.BatchChannels {
+		pc, err := p.openPooled()
+		if err != nil {
+			klog.V(1).Infof("openPooled %s failed. Err: %v\n", p.GetName(), err)
+			break
+		}
+		p.pool = append(p.pool, pc)
+	}
+
+	return append([]*pooledChannel{}, p.pool...)
+}
+
+/*
+	The confirmations of a pooled channel fail with it, they are not kept for
+	republishing since the caller of SendMessages gets the error
+*/
+func (p *Publisher) openPooled() (*pooledChannel, error) {
+	channel, err := p.options.OpenChannel()
+	if err != nil {
+		return nil, err
+	}
+
+	err = p.listen(channel, false)
+	if err != nil {
+		channel.Close()
+		return nil, err
+	}
+
+	return &pooledChannel{channel: channel}, nil
+}
+
+func (p *Publisher) closePool() {
+	p.poolMu.Lock()
+	pool := p.pool
+	p.pool = nil
+	p.poolMu.Unlock()
+
+	for _, pc := range pool {
+		pc.channel.Close()
+	}
+}
+
+/*
+	Sends the message on a pooled channel, or spools it when the channel turns out
+	to be closed
+*/
+func (p *Publisher) sendPooled(pc *pooledChannel, key string, publishing amqp.Publishing) (*confirmation, bool, error) {
+	err := p.throttle(len(publishing.Body), p.options.RatePolicy == interfaces.RatePolicyBlock)
+	if err != nil {
+		return nil, false, err
+	}
+
+	err = p.acquireSlot()
+	if err != nil {
+		klog.V(1).Infof("acquireSlot failed. Err: %v\n", err)
+		return nil, false, err
+	}
+
+	pc.mu.Lock()
+	pending, err := p.sendOn(pc.channel, key, publishing)
+	pc.mu.Unlock()
+	if err != nil && p.spool != nil && errors.Is(err, amqp.ErrClosed) {
+		return nil, true, spoolResult(p.spoolMessage(key, publishing))
+	}
+
+	return pending, false, err
+}
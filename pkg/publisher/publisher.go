@@ -5,19 +5,124 @@ package publisher
 
 import (
 	"context"
+	"net/url"
+	"path/filepath"
+	"sync/atomic"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 	klog "k8s.io/klog/v2"
 
+	claimcheck "github.com/dvonthenen/rabbitmq-manager/pkg/claimcheck"
 	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	compression "github.com/dvonthenen/rabbitmq-manager/pkg/compression"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	ratelimit "github.com/dvonthenen/rabbitmq-manager/pkg/ratelimit"
+	signing "github.com/dvonthenen/rabbitmq-manager/pkg/signing"
+	spool "github.com/dvonthenen/rabbitmq-manager/pkg/spool"
 )
 
-func New(options PublisherOptions) *Publisher {
+func New(options PublisherOptions) (*Publisher, error) {
+	err := common.ValidateRoutingKey(options.RoutingKey)
+	if err != nil {
+		klog.V(1).Infof("ValidateRoutingKey %s failed. Err: %v\n", options.Name, err)
+		return nil, err
+	}
+
+	err = options.Headers.Validate()
+	if err != nil {
+		klog.V(1).Infof("Headers.Validate %s failed. Err: %v\n", options.Name, err)
+		return nil, err
+	}
+
+	if options.Republish && !options.ConfirmMode {
+		klog.V(1).Infof("Publisher %s republishes without confirm mode\n", options.Name)
+		return nil, ErrRepublishRequiresConfirm
+	}
+	if compression.TypeToEncoding(options.Compression) == "" && options.Compression != interfaces.CompressionTypeNone {
+		klog.V(1).Infof("Publisher %s has an unknown compression type\n", options.Name)
+		return nil, compression.ErrUnknownCompression
+	}
+	if options.CompressionThreshold < 0 {
+		klog.V(1).Infof("Publisher %s has a negative compression threshold\n", options.Name)
+		return nil, ErrInvalidCompressionThreshold
+	}
+	if options.ClaimCheckThreshold < 0 {
+		klog.V(1).Infof("Publisher %s has a negative claim check threshold\n", options.Name)
+		return nil, ErrInvalidClaimCheckThreshold
+	}
+	if len(options.SignedHeaders) > 0 && options.Signer == nil {
+		klog.V(1).Infof("Publisher %s has SignedHeaders without a Signer\n", options.Name)
+		return nil, ErrSignedHeadersRequireSigner
+	}
+	err = signing.ValidateHeaderNames(options.SignedHeaders)
+	if err != nil {
+		klog.V(1).Infof("ValidateHeaderNames %s failed. Err: %v\n", options.Name, err)
+		return nil, err
+	}
+	if options.MaxUnconfirmed < 0 {
+		klog.V(1).Infof("Publisher %s has a negative MaxUnconfirmed\n", options.Name)
+		return nil, ErrInvalidMaxUnconfirmed
+	}
+	if options.BatchChannels < 0 {
+		klog.V(1).Infof("Publisher %s has a negative BatchChannels\n", options.Name)
+		return nil, ErrInvalidBatchChannels
+	}
+	if options.BatchChannels > 0 && options.OpenChannel == nil {
+		klog.V(1).Infof("Publisher %s has BatchChannels without OpenChannel\n", options.Name)
+		return nil, ErrBatchChannelsRequireOpenChannel
+	}
+
+	limiter, err := ratelimit.NewThrottle(options.RateLimit)
+	if err != nil {
+		klog.V(1).Infof("NewThrottle %s failed. Err: %v\n", options.Name, err)
+		return nil, err
+	}
+
 	rabbit := &Publisher{
-		options: options,
-		channel: options.Channel,
+		options:        options,
+		limiter:        limiter,
+		channel:        options.Channel,
+		confirmTimeout: options.ConfirmTimeout,
+		pending:        make(map[ledgerKey]*confirmation),
+		// seeded so messages spooled by an earlier run do not collide
+		returnSequence: uint64(time.Now().UnixNano()),
+	}
+	if rabbit.confirmTimeout == 0 {
+		rabbit.confirmTimeout = DefaultConfirmTimeout
+	}
+	rabbit.compressionThreshold = options.CompressionThreshold
+	if rabbit.compressionThreshold == 0 {
+		rabbit.compressionThreshold = compression.DefaultThreshold
+	}
+	rabbit.claimCheckThreshold = options.ClaimCheckThreshold
+	if rabbit.claimCheckThreshold == 0 {
+		rabbit.claimCheckThreshold = claimcheck.DefaultThreshold
+	}
+
+	// the ledger is bounded so a long outage cannot exhaust memory
+	if options.Republish {
+		maxUnconfirmed := options.MaxUnconfirmed
+		if maxUnconfirmed == 0 {
+			maxUnconfirmed = DefaultMaxUnconfirmed
+		}
+		rabbit.ledgerSlots = make(chan struct{}, maxUnconfirmed)
 	}
-	return rabbit
+
+	if options.Spool {
+		directory := options.SpoolDirectory
+		if directory == "" {
+			directory = filepath.Join(spool.DefaultDirectory, url.PathEscape(options.Name))
+		}
+
+		rabbit.spool, err = spool.Open(directory, options.SpoolSegmentSize, options.SpoolMaxBytes)
+		if err != nil {
+			klog.V(1).Infof("spool.Open %s failed. Err: %v\n", directory, err)
+			return nil, err
+		}
+	}
+
+	return rabbit, nil
 }
 
 func (p *Publisher) GetName() string {
@@ -27,6 +132,28 @@ func (p *Publisher) GetName() string {
 func (p *Publisher) Init() error {
 	klog.V(6).Infof("Publisher.Init ENTER\n")
 
+	err := p.declare()
+	if err != nil {
+		klog.V(1).Infof("declare failed. Err: %v\n", err)
+		klog.V(6).Infof("Publisher.Init LEAVE\n")
+		return err
+	}
+
+	// messages spooled by an earlier run or outage go out first
+	err = p.replaySpool()
+	if err != nil {
+		klog.V(1).Infof("replaySpool failed. Err: %v\n", err)
+		klog.V(6).Infof("Publisher.Init LEAVE\n")
+		return err
+	}
+
+	klog.V(4).Infof("Publisher.Init Succeeded\n")
+	klog.V(6).Infof("Publisher.Init LEAVE\n")
+
+	return nil
+}
+
+func (p *Publisher) declare() error {
 	klog.V(3).Infof("ExchangeDeclare: %s\n", p.GetName())
 	err := p.channel.ExchangeDeclare(
 		p.options.Name, // name
@@ -39,12 +166,14 @@ func (p *Publisher) Init() error {
 	)
 	if err != nil {
 		klog.V(1).Infof("ExchangeDeclare failed. Err: %v\n", err)
-		klog.V(6).Infof("Publisher.Init LEAVE\n")
 		return err
 	}
 
-	klog.V(4).Infof("Publisher.Init Succeeded\n")
-	klog.V(6).Infof("Publisher.Init LEAVE\n")
+	err = p.enableNotifications()
+	if err != nil {
+		klog.V(1).Infof("enableNotifications failed. Err: %v\n", err)
+		return err
+	}
 
 	return nil
 }
@@ -64,33 +193,380 @@ func (p *Publisher) Retry() error {
 	return err
 }
 
+/*
+	Moves the publisher onto a new channel after its old one closed. With
+	Republish set, everything still unconfirmed is published again on the new
+	channel, followed by whatever was spooled during the outage.
+*/
+func (p *Publisher) Recover(channel *amqp.Channel) error {
+	klog.V(6).Infof("Publisher.Recover ENTER\n")
+	klog.V(3).Infof("Publisher.Recover %s called\n", p.GetName())
+
+	err := p.swapChannel(channel)
+	if err != nil {
+		klog.V(1).Infof("Publisher.Recover failed. Err: %v\n", err)
+		klog.V(6).Infof("Publisher.Recover LEAVE\n")
+		return err
+	}
+
+	err = p.replaySpool()
+	if err != nil {
+		klog.V(1).Infof("replaySpool failed. Err: %v\n", err)
+		klog.V(6).Infof("Publisher.Recover LEAVE\n")
+		return err
+	}
+
+	klog.V(4).Infof("Publisher.Recover Succeeded\n")
+	klog.V(6).Infof("Publisher.Recover LEAVE\n")
+
+	return nil
+}
+
+func (p *Publisher) swapChannel(channel *amqp.Channel) error {
+	p.publishMu.Lock()
+	defer p.publishMu.Unlock()
+
+	// whatever the pool left unconfirmed is republished on the new channel
+	p.closePool()
+
+	if p.channel != nil {
+		p.channel.Close()
+	}
+	p.channel = channel
+
+	err := p.declare()
+	if err != nil {
+		klog.V(1).Infof("declare failed. Err: %v\n", err)
+		return err
+	}
+
+	if p.options.Republish {
+		err = p.republish()
+		if err != nil {
+			klog.V(1).Infof("republish failed. Err: %v\n", err)
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (p *Publisher) IsClosed() bool {
+	p.publishMu.Lock()
+	defer p.publishMu.Unlock()
+
+	return p.channel == nil || p.channel.IsClosed()
+}
+
 func (p *Publisher) SendMessage(data []byte) error {
-	klog.V(6).Infof("Publisher.SendMessage ENTER\n")
-	klog.V(3).Infof("Publishing to: %s\n", p.options.Name)
-	klog.V(4).Infof("Data: %s\n", string(data))
+	return p.publish(interfaces.Message{
+		Body: data,
+	})
+}
+
+func (p *Publisher) SendMessageWithKey(key string, data []byte) error {
+	return p.publish(interfaces.Message{
+		RoutingKey: key,
+		Body:       data,
+	})
+}
+
+func (p *Publisher) SendMessageWithHeaders(headers amqp.Table, data []byte) error {
+	return p.publish(interfaces.Message{
+		Headers: headers,
+		Body:    data,
+	})
+}
+
+func (p *Publisher) SendMessageWithProperties(msg interfaces.Message) error {
+	return p.publish(msg)
+}
+
+/*
+	Merges the per message headers over the publisher default headers
+*/
+func (p *Publisher) mergeHeaders(headers amqp.Table) amqp.Table {
+	if len(p.options.Headers) == 0 {
+		return headers
+	}
+
+	merged := make(amqp.Table)
+	for key, value := range p.options.Headers {
+		merged[key] = value
+	}
+	for key, value := range headers {
+		merged[key] = value
+	}
+	return merged
+}
+
+/*
+	Copies the headers before a message gets headers of its own, the table may be
+	shared with the caller or the publisher defaults
+*/
+func copyHeaders(headers amqp.Table) amqp.Table {
+	copied := make(amqp.Table, len(headers)+4)
+	for key, value := range headers {
+		copied[key] = value
+	}
+	return copied
+}
+
+/*
+	Resolves the routing key and fills in the publisher defaults for any message
+	property that was left unset
+*/
+func (p *Publisher) prepare(msg *interfaces.Message) (string, amqp.Publishing, error) {
+	key := msg.RoutingKey
+	if key == "" {
+		key = p.options.RoutingKey
+	}
+
+	err := common.ValidateRoutingKey(key)
+	if err != nil {
+		return "", amqp.Publishing{}, err
+	}
+	if msg.DeliveryMode > amqp.Persistent {
+		return "", amqp.Publishing{}, ErrInvalidDeliveryMode
+	}
+
+	publishing := amqp.Publishing{
+		Headers:         p.mergeHeaders(msg.Headers),
+		ContentType:     msg.ContentType,
+		ContentEncoding: msg.ContentEncoding,
+		DeliveryMode:    msg.DeliveryMode,
+		Priority:        msg.Priority,
+		CorrelationId:   msg.CorrelationId,
+		ReplyTo:         msg.ReplyTo,
+		Expiration:      msg.Expiration,
+		MessageId:       msg.MessageId,
+		Timestamp:       msg.Timestamp,
+		Type:            msg.Type,
+		UserId:          msg.UserId,
+		AppId:           msg.AppId,
+		Body:            msg.Body,
+	}
+
+	if publishing.ContentType == "" {
+		publishing.ContentType = p.options.ContentType
+	}
+	if publishing.ContentType == "" {
+		publishing.ContentType = DefaultContentType
+	}
+	if publishing.AppId == "" {
+		publishing.AppId = p.options.AppId
+	}
+	// messages on a durable exchange should survive a broker restart
+	if publishing.DeliveryMode == 0 && p.options.Durable {
+		publishing.DeliveryMode = amqp.Persistent
+	}
+	if publishing.MessageId == "" && !p.options.NoMessageId {
+		publishing.MessageId, err = common.NewMessageId()
+		if err != nil {
+			return "", amqp.Publishing{}, err
+		}
+	}
+	if publishing.Timestamp.IsZero() && !p.options.NoTimestamp {
+		publishing.Timestamp = time.Now()
+	}
+
+	// a return carries no delivery tag, the header is how it finds its publish
+	if p.options.ConfirmMode && p.options.Mandatory {
+		publishing.Headers = copyHeaders(publishing.Headers)
+		publishing.Headers[common.HeaderPublishSequence] = int64(atomic.AddUint64(&p.returnSequence, 1))
+	}
+
+	err = p.wrap(&publishing)
+	if err != nil {
+		return "", amqp.Publishing{}, err
+	}
+
+	return key, publishing, nil
+}
+
+/*
+	Publishes the message on the publisher channel. The wait for room in the
+	ledger happens before taking publishMu so it cannot hold up IsClosed or a
+	recovery.
+*/
+func (p *Publisher) send(key string, publishing amqp.Publishing) (*confirmation, error) {
+	err := p.acquireSlot()
+	if err != nil {
+		klog.V(1).Infof("acquireSlot failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	p.publishMu.Lock()
+	defer p.publishMu.Unlock()
+
+	return p.sendOn(p.channel, key, publishing)
+}
+
+/*
+	Publishes the message and, in confirm mode, registers a pending confirmation
+	for it. Caller must hold a ledger slot and serialize publishing on the channel
+	so the delivery tag matches the publish.
+*/
+func (p *Publisher) sendOn(channel *amqp.Channel, key string, publishing amqp.Publishing) (*confirmation, error) {
+	klog.V(3).Infof("Publishing to: %s (key: %s, id: %s)\n", p.options.Name, key, publishing.MessageId)
+
+	var pending *confirmation
+	if p.options.ConfirmMode {
+		pending = newConfirmation(channel, atomic.AddUint64(&p.sequence, 1), key, publishing)
+
+		p.pendingMu.Lock()
+		p.pending[ledgerKey{pending.channel, pending.deliveryTag}] = pending
+		p.pendingMu.Unlock()
+	}
 
 	ctx := context.Background()
-	err := p.channel.PublishWithContext(ctx,
-		p.options.Name, // exchange
-		"",             // routing key
-		false,          // mandatory
-		false,          // immediate
-		amqp.Publishing{
-			ContentType: "text/plain",
-			Body:        data,
-		})
+	err := channel.PublishWithContext(ctx,
+		p.options.Name,      // exchange
+		key,                 // routing key
+		p.options.Mandatory, // mandatory
+		false,               // immediate
+		publishing,
+	)
 	if err != nil {
 		klog.V(1).Infof("PublishWithContext failed. Err: %v\n", err)
+		if pending != nil {
+			p.pendingMu.Lock()
+			delete(p.pending, ledgerKey{pending.channel, pending.deliveryTag})
+			p.pendingMu.Unlock()
+			p.releaseSlot()
+		}
+		return nil, err
+	}
+	atomic.AddUint64(&p.stats.published, 1)
+
+	return pending, nil
+}
+
+func (p *Publisher) publish(msg interfaces.Message) error {
+	klog.V(6).Infof("Publisher.SendMessage ENTER\n")
+
+	key, publishing, err := p.prepare(&msg)
+	if err != nil {
+		klog.V(1).Infof("prepare failed. Err: %v\n", err)
 		klog.V(6).Infof("Publisher.SendMessage LEAVE\n")
 		return err
 	}
 
-	klog.V(4).Infof("Publisher.SendMessage %s succeeded\n%s\n", p.GetName(), string(data))
+	klog.V(4).Infof("Data: %d bytes\n", len(publishing.Body))
+
+	// ErrSpooled tells the caller the message is not with the broker yet
+	pending, spooled, err := p.sendOrSpool(key, publishing)
+	if spooled {
+		klog.V(3).Infof("Publisher.SendMessage %s spooled %s\n", p.GetName(), publishing.MessageId)
+		klog.V(6).Infof("Publisher.SendMessage LEAVE\n")
+		return err
+	}
+	if err != nil {
+		klog.V(1).Infof("send failed. Err: %v\n", err)
+		klog.V(6).Infof("Publisher.SendMessage LEAVE\n")
+		return err
+	}
+
+	if pending != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), p.confirmTimeout)
+		defer cancel()
+
+		err = pending.Wait(ctx)
+		if err != nil {
+			klog.V(1).Infof("Wait for confirmation %d failed. Err: %v\n", pending.DeliveryTag(), err)
+			klog.V(6).Infof("Publisher.SendMessage LEAVE\n")
+			return err
+		}
+	}
+
+	klog.V(4).Infof("Publisher.SendMessage %s succeeded\n", p.GetName())
 	klog.V(6).Infof("Publisher.SendMessage LEAVE\n")
 
 	return nil
 }
 
+/*
+	Publishes on a channel owned by the caller, such as a channel in transaction
+	mode. The publisher defaults still apply but confirms, returns and the spool
+	are left to the owner of the channel.
+*/
+func (p *Publisher) PublishOn(channel *amqp.Channel, msg interfaces.Message) error {
+	key, publishing, err := p.prepare(&msg)
+	if err != nil {
+		klog.V(1).Infof("prepare failed. Err: %v\n", err)
+		return err
+	}
+
+	err = p.throttle(len(publishing.Body), p.options.RatePolicy == interfaces.RatePolicyBlock)
+	if err != nil {
+		klog.V(1).Infof("throttle failed. Err: %v\n", err)
+		return err
+	}
+
+	klog.V(3).Infof("Publishing on channel to: %s (key: %s, id: %s)\n", p.options.Name, key, publishing.MessageId)
+
+	err = channel.PublishWithContext(context.Background(),
+		p.options.Name, // exchange
+		key,            // routing key
+		false,          // mandatory
+		false,          // immediate
+		publishing,
+	)
+	if err != nil {
+		klog.V(1).Infof("PublishWithContext failed. Err: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+func (p *Publisher) SendMessageAsync(data []byte) (interfaces.Confirmation, error) {
+	return p.SendMessageWithPropertiesAsync(interfaces.Message{
+		Body: data,
+	})
+}
+
+/*
+	Publishes without waiting for the broker, the returned Confirmation resolves
+	once the broker acks or nacks the message. Requires confirm mode.
+*/
+func (p *Publisher) SendMessageWithPropertiesAsync(msg interfaces.Message) (interfaces.Confirmation, error) {
+	klog.V(6).Infof("Publisher.SendMessageAsync ENTER\n")
+
+	if !p.options.ConfirmMode {
+		klog.V(1).Infof("Publisher %s is not in confirm mode\n", p.GetName())
+		klog.V(6).Infof("Publisher.SendMessageAsync LEAVE\n")
+		return nil, ErrConfirmModeDisabled
+	}
+
+	key, publishing, err := p.prepare(&msg)
+	if err != nil {
+		klog.V(1).Infof("prepare failed. Err: %v\n", err)
+		klog.V(6).Infof("Publisher.SendMessageAsync LEAVE\n")
+		return nil, err
+	}
+
+	klog.V(4).Infof("Data: %d bytes\n", len(publishing.Body))
+
+	// a spooled message has no confirmation until it is replayed
+	pending, spooled, err := p.sendOrSpool(key, publishing)
+	if spooled {
+		klog.V(3).Infof("Publisher.SendMessageAsync %s spooled %s\n", p.GetName(), publishing.MessageId)
+		klog.V(6).Infof("Publisher.SendMessageAsync LEAVE\n")
+		return nil, err
+	}
+	if err != nil {
+		klog.V(1).Infof("send failed. Err: %v\n", err)
+		klog.V(6).Infof("Publisher.SendMessageAsync LEAVE\n")
+		return nil, err
+	}
+
+	klog.V(4).Infof("Publisher.SendMessageAsync %s published %d\n", p.GetName(), pending.DeliveryTag())
+	klog.V(6).Infof("Publisher.SendMessageAsync LEAVE\n")
+
+	return pending, nil
+}
+
 func (p *Publisher) teardownMinusChannel() error {
 	var retErr error
 	retErr = nil
@@ -128,11 +604,24 @@ func (p *Publisher) Teardown() error {
 		retErr = err
 	}
 
+	p.closePool()
 	if p.channel != nil {
 		p.channel.Close()
 		p.channel = nil
 	}
 
+	// nothing is left to republish on
+	p.failPending(nil, ErrChannelClosed)
+
+	// the spool stays on disk for the next run
+	if p.spool != nil {
+		err = p.spool.Close()
+		if err != nil {
+			klog.V(1).Infof("spool.Close failed. Err: %v\n", err)
+			retErr = err
+		}
+	}
+
 	if retErr == nil {
 		klog.V(4).Infof("Publisher.Teardown Succeeded\n")
 	} else {
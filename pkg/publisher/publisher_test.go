@@ -0,0 +1,133 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+var messageIdPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestPrepareDefaults(t *testing.T) {
+	timestamp := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		options   interfaces.PublisherOptions
+		msg       interfaces.Message
+		messageId string
+		timestamp bool
+		want      amqp.Publishing
+	}{
+		{
+			name:      "defaults",
+			messageId: "generated",
+			timestamp: true,
+			want:      amqp.Publishing{ContentType: DefaultContentType},
+		},
+		{
+			name:      "publisher defaults",
+			options:   interfaces.PublisherOptions{ContentType: "application/json", AppId: "orders", Durable: true},
+			messageId: "generated",
+			timestamp: true,
+			want:      amqp.Publishing{ContentType: "application/json", AppId: "orders", DeliveryMode: amqp.Persistent},
+		},
+		{
+			name:    "caller values are kept",
+			options: interfaces.PublisherOptions{ContentType: "application/json", AppId: "orders", Durable: true},
+			msg: interfaces.Message{
+				MessageId:    "id-1",
+				Timestamp:    timestamp,
+				ContentType:  "application/xml",
+				AppId:        "billing",
+				DeliveryMode: amqp.Transient,
+			},
+			messageId: "id-1",
+			want:      amqp.Publishing{ContentType: "application/xml", AppId: "billing", DeliveryMode: amqp.Transient, Timestamp: timestamp},
+		},
+		{
+			name:    "no message id or timestamp",
+			options: interfaces.PublisherOptions{NoMessageId: true, NoTimestamp: true},
+			want:    amqp.Publishing{ContentType: DefaultContentType},
+		},
+		{
+			name:      "caller values without defaults",
+			options:   interfaces.PublisherOptions{NoMessageId: true, NoTimestamp: true},
+			msg:       interfaces.Message{MessageId: "id-2", Timestamp: timestamp},
+			messageId: "id-2",
+			want:      amqp.Publishing{ContentType: DefaultContentType, Timestamp: timestamp},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			options.Name = "test"
+			p, err := New(PublisherOptions{PublisherOptions: &options})
+			if err != nil {
+				t.Fatalf("New failed. Err: %v", err)
+			}
+
+			before := time.Now()
+			_, publishing, err := p.prepare(&tt.msg)
+			if err != nil {
+				t.Fatalf("prepare failed. Err: %v", err)
+			}
+
+			switch tt.messageId {
+			case "generated":
+				if !messageIdPattern.MatchString(publishing.MessageId) {
+					t.Fatalf("MessageId = %q, want a generated UUID", publishing.MessageId)
+				}
+			default:
+				if publishing.MessageId != tt.messageId {
+					t.Fatalf("MessageId = %q, want %q", publishing.MessageId, tt.messageId)
+				}
+			}
+
+			if tt.timestamp {
+				if publishing.Timestamp.Before(before) || publishing.Timestamp.After(time.Now()) {
+					t.Fatalf("Timestamp = %v, want the publish time", publishing.Timestamp)
+				}
+			} else if !publishing.Timestamp.Equal(tt.want.Timestamp) {
+				t.Fatalf("Timestamp = %v, want %v", publishing.Timestamp, tt.want.Timestamp)
+			}
+
+			if publishing.ContentType != tt.want.ContentType {
+				t.Fatalf("ContentType = %q, want %q", publishing.ContentType, tt.want.ContentType)
+			}
+			if publishing.AppId != tt.want.AppId {
+				t.Fatalf("AppId = %q, want %q", publishing.AppId, tt.want.AppId)
+			}
+			if publishing.DeliveryMode != tt.want.DeliveryMode {
+				t.Fatalf("DeliveryMode = %d, want %d", publishing.DeliveryMode, tt.want.DeliveryMode)
+			}
+		})
+	}
+}
+
+func TestPrepareUniqueMessageIds(t *testing.T) {
+	p, err := New(PublisherOptions{PublisherOptions: &interfaces.PublisherOptions{Name: "test"}})
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for i := 0; i < 100; i++ {
+		_, publishing, err := p.prepare(&interfaces.Message{})
+		if err != nil {
+			t.Fatalf("prepare failed. Err: %v", err)
+		}
+		if seen[publishing.MessageId] {
+			t.Fatalf("MessageId %s generated twice", publishing.MessageId)
+		}
+		seen[publishing.MessageId] = true
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"context"
+	"sync/atomic"
+
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Holds a message of the given size back until it fits within the rate limit,
+	or fails it straight away when block is false
+*/
+func (p *Publisher) throttle(size int, block bool) error {
+	if !p.limiter.Limited() || p.limiter.Allow(size) {
+		return nil
+	}
+
+	if !block {
+		klog.V(3).Infof("Publisher %s over its rate limit\n", p.GetName())
+		atomic.AddUint64(&p.stats.rateLimited, 1)
+		return ErrRateLimited
+	}
+
+	waited, err := p.limiter.Wait(context.Background(), size)
+	atomic.AddUint64(&p.stats.throttled, 1)
+	atomic.AddInt64(&p.stats.throttleWait, int64(waited))
+
+	return err
+}
+
+/*
+	Changes the rate limit at runtime
+*/
+func (p *Publisher) SetRateLimit(limit interfaces.RateLimit) error {
+	klog.V(6).Infof("Publisher.SetRateLimit ENTER\n")
+
+	err := p.limiter.SetLimit(limit)
+	if err != nil {
+		klog.V(1).Infof("SetRateLimit %s failed. Err: %v\n", p.GetName(), err)
+		klog.V(6).Infof("Publisher.SetRateLimit LEAVE\n")
+		return err
+	}
+
+	klog.V(4).Infof("Publisher.SetRateLimit %s Succeeded\n", p.GetName())
+	klog.V(6).Infof("Publisher.SetRateLimit LEAVE\n")
+
+	return nil
+}
@@ -0,0 +1,221 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	spool "github.com/dvonthenen/rabbitmq-manager/pkg/spool"
+)
+
+/*
+	Spools the message when the broker is unreachable, or when earlier messages
+	are still waiting to be replayed so the publish order is kept. A spool left
+	behind on an open channel, such as by a replay that failed, is replayed again
+	in the background.
+*/
+func (p *Publisher) spoolIfUnavailable(key string, publishing amqp.Publishing) (bool, error) {
+	if p.spool == nil {
+		return false, nil
+	}
+
+	p.spoolMu.Lock()
+	defer p.spoolMu.Unlock()
+
+	closed := p.IsClosed()
+	if !closed && p.spool.Empty() {
+		return false, nil
+	}
+
+	err := p.appendSpool(key, publishing)
+	if !closed {
+		p.triggerReplay()
+	}
+
+	return true, err
+}
+
+/*
+	Starts a background replay unless one is already running
+*/
+func (p *Publisher) triggerReplay() {
+	if !atomic.CompareAndSwapInt32(&p.replaying, 0, 1) {
+		return
+	}
+
+	go func() {
+		defer atomic.StoreInt32(&p.replaying, 0)
+
+		err := p.replaySpool()
+		if err != nil {
+			klog.V(1).Infof("replaySpool failed. Err: %v\n", err)
+		}
+	}()
+}
+
+/*
+	Publishes whatever is still spooled on the current channel. Recover does this
+	already, it is for retrying after a replay failed on a channel that stayed
+	open.
+*/
+func (p *Publisher) ReplaySpool() error {
+	klog.V(6).Infof("Publisher.ReplaySpool ENTER\n")
+
+	err := p.replaySpool()
+	if err != nil {
+		klog.V(1).Infof("replaySpool failed. Err: %v\n", err)
+		klog.V(6).Infof("Publisher.ReplaySpool LEAVE\n")
+		return err
+	}
+
+	klog.V(6).Infof("Publisher.ReplaySpool LEAVE\n")
+
+	return nil
+}
+
+/*
+	Reports whether messages are waiting in the spool
+*/
+func (p *Publisher) HasSpooled() bool {
+	if p.spool == nil {
+		return false
+	}
+
+	p.spoolMu.Lock()
+	defer p.spoolMu.Unlock()
+
+	return !p.spool.Empty()
+}
+
+/*
+	Sends the message, or spools it when the broker turns out to be unreachable.
+	Reports whether the message went to the spool, with ErrSpooled when spooling
+	succeeded.
+*/
+func (p *Publisher) sendOrSpool(key string, publishing amqp.Publishing) (*confirmation, bool, error) {
+	spooled, err := p.spoolIfUnavailable(key, publishing)
+	if spooled {
+		return nil, true, spoolResult(err)
+	}
+
+	err = p.throttle(len(publishing.Body), p.options.RatePolicy == interfaces.RatePolicyBlock)
+	if err != nil {
+		return nil, false, err
+	}
+
+	pending, err := p.send(key, publishing)
+	if err != nil && p.spool != nil && errors.Is(err, amqp.ErrClosed) {
+		return nil, true, spoolResult(p.spoolMessage(key, publishing))
+	}
+
+	return pending, false, err
+}
+
+func spoolResult(err error) error {
+	if err != nil {
+		return err
+	}
+	return ErrSpooled
+}
+
+func (p *Publisher) spoolMessage(key string, publishing amqp.Publishing) error {
+	p.spoolMu.Lock()
+	defer p.spoolMu.Unlock()
+
+	return p.appendSpool(key, publishing)
+}
+
+/*
+	Caller must hold spoolMu
+*/
+func (p *Publisher) appendSpool(key string, publishing amqp.Publishing) error {
+	err := p.spool.Append(&spool.Record{
+		RoutingKey: key,
+		Publishing: publishing,
+	})
+	if err != nil {
+		klog.V(1).Infof("spool.Append failed. Err: %v\n", err)
+		return err
+	}
+
+	atomic.AddUint64(&p.stats.spooled, 1)
+	return nil
+}
+
+/*
+	Publishes the spooled messages in order. A record is only removed from the
+	spool once it is published, and confirmed in confirm mode, so a failure part
+	way leaves the rest for the next attempt. New messages keep going to the spool
+	until it is drained.
+*/
+func (p *Publisher) replaySpool() error {
+	if p.spool == nil {
+		return nil
+	}
+
+	p.spoolMu.Lock()
+	defer p.spoolMu.Unlock()
+
+	replayed := 0
+	for {
+		record, err := p.spool.Peek()
+		if err == spool.ErrSpoolEmpty {
+			break
+		}
+		if err != nil {
+			klog.V(1).Infof("spool.Peek failed. Err: %v\n", err)
+			return err
+		}
+
+		// replay always waits, failing would leave the record stuck at the head
+		err = p.throttle(len(record.Publishing.Body), true)
+		if err != nil {
+			klog.V(1).Infof("throttle failed. Err: %v\n", err)
+			return err
+		}
+
+		pending, err := p.send(record.RoutingKey, record.Publishing)
+		if err != nil {
+			klog.V(1).Infof("send failed. Err: %v\n", err)
+			return err
+		}
+
+		if pending != nil {
+			ctx, cancel := context.WithTimeout(context.Background(), p.confirmTimeout)
+			err = pending.Wait(ctx)
+			cancel()
+
+			// the broker settled nacked and returned messages, sending them again won't help
+			var nackErr *NackError
+			var returnErr *ReturnError
+			switch {
+			case err == nil:
+			case errors.As(err, &nackErr), errors.As(err, &returnErr):
+				klog.V(1).Infof("Spooled message %s dropped. Err: %v\n", record.Publishing.MessageId, err)
+			default:
+				klog.V(1).Infof("Wait for spooled message %s failed. Err: %v\n", record.Publishing.MessageId, err)
+				return err
+			}
+		}
+
+		err = p.spool.Commit()
+		if err != nil {
+			klog.V(1).Infof("spool.Commit failed. Err: %v\n", err)
+			return err
+		}
+		replayed++
+	}
+
+	if replayed > 0 {
+		klog.V(3).Infof("Replayed %d spooled messages on %s\n", replayed, p.GetName())
+	}
+
+	return nil
+}
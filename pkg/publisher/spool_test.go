@@ -0,0 +1,53 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"errors"
+	"testing"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+func TestSpooledWhileClosed(t *testing.T) {
+	p, err := New(PublisherOptions{
+		PublisherOptions: &interfaces.PublisherOptions{
+			Name:           "test",
+			ConfirmMode:    true,
+			Spool:          true,
+			SpoolDirectory: t.TempDir(),
+		},
+	})
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+	defer p.spool.Close()
+
+	err = p.SendMessage([]byte("one"))
+	if !errors.Is(err, ErrSpooled) {
+		t.Fatalf("SendMessage() err = %v, want ErrSpooled", err)
+	}
+
+	_, err = p.SendMessageAsync([]byte("two"))
+	if !errors.Is(err, ErrSpooled) {
+		t.Fatalf("SendMessageAsync() err = %v, want ErrSpooled", err)
+	}
+
+	results, err := p.SendMessages([]interfaces.Message{{Body: []byte("three")}, {Body: []byte("four")}})
+	if !errors.Is(err, ErrSpooled) {
+		t.Fatalf("SendMessages() err = %v, want ErrSpooled", err)
+	}
+	for i, result := range results {
+		if !errors.Is(result.Err, ErrSpooled) {
+			t.Fatalf("result %d err = %v, want ErrSpooled", i, result.Err)
+		}
+	}
+
+	if !p.HasSpooled() {
+		t.Fatalf("HasSpooled() = false after spooling")
+	}
+	if p.stats.spooled != 4 {
+		t.Fatalf("spooled = %d, want 4", p.stats.spooled)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"sync/atomic"
+	"time"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+type stats struct {
+	published uint64
+	spooled   uint64
+
+	throttled    uint64
+	throttleWait int64
+	rateLimited  uint64
+
+	compressed        uint64
+	uncompressedBytes uint64
+	compressedBytes   uint64
+	compressionTime   int64
+
+	encrypted uint64
+	signed    uint64
+
+	claimChecked      uint64
+	claimCheckedBytes uint64
+}
+
+func (p *Publisher) GetStats() interfaces.PublisherStats {
+	uncompressedBytes := atomic.LoadUint64(&p.stats.uncompressedBytes)
+	compressedBytes := atomic.LoadUint64(&p.stats.compressedBytes)
+
+	var ratio float64
+	if compressedBytes > 0 {
+		ratio = float64(uncompressedBytes) / float64(compressedBytes)
+	}
+
+	return interfaces.PublisherStats{
+		Published:    atomic.LoadUint64(&p.stats.published),
+		Spooled:      atomic.LoadUint64(&p.stats.spooled),
+		Throttled:    atomic.LoadUint64(&p.stats.throttled),
+		ThrottleWait: time.Duration(atomic.LoadInt64(&p.stats.throttleWait)),
+		RateLimited:  atomic.LoadUint64(&p.stats.rateLimited),
+
+		Compressed:        atomic.LoadUint64(&p.stats.compressed),
+		UncompressedBytes: uncompressedBytes,
+		CompressedBytes:   compressedBytes,
+		CompressionRatio:  ratio,
+		CompressionTime:   time.Duration(atomic.LoadInt64(&p.stats.compressionTime)),
+
+		Encrypted: atomic.LoadUint64(&p.stats.encrypted),
+		Signed:    atomic.LoadUint64(&p.stats.signed),
+
+		ClaimChecked:      atomic.LoadUint64(&p.stats.claimChecked),
+		ClaimCheckedBytes: atomic.LoadUint64(&p.stats.claimCheckedBytes),
+	}
+}
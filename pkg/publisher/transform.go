@@ -0,0 +1,207 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package publisher
+
+import (
+	"encoding/base64"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	claimcheck "github.com/dvonthenen/rabbitmq-manager/pkg/claimcheck"
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	compression "github.com/dvonthenen/rabbitmq-manager/pkg/compression"
+	encryption "github.com/dvonthenen/rabbitmq-manager/pkg/encryption"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	signing "github.com/dvonthenen/rabbitmq-manager/pkg/signing"
+)
+
+/*
+	Applies the payload transformations to a prepared message, the subscriber
+	reverses them in the opposite order
+*/
+func (p *Publisher) wrap(publishing *amqp.Publishing) error {
+	err := p.compress(publishing)
+	if err != nil {
+		klog.V(1).Infof("compress failed. Err: %v\n", err)
+		return err
+	}
+
+	err = p.encrypt(publishing)
+	if err != nil {
+		klog.V(1).Infof("encrypt failed. Err: %v\n", err)
+		return err
+	}
+
+	err = p.claimCheck(publishing)
+	if err != nil {
+		klog.V(1).Infof("claimCheck failed. Err: %v\n", err)
+		return err
+	}
+
+	err = p.sign(publishing)
+	if err != nil {
+		klog.V(1).Infof("sign failed. Err: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+/*
+	Compresses bodies over the threshold and marks them with a content-encoding.
+	Bodies that already have an encoding or would not shrink are left alone.
+*/
+func (p *Publisher) compress(publishing *amqp.Publishing) error {
+	if p.options.Compression == interfaces.CompressionTypeNone {
+		return nil
+	}
+	if publishing.ContentEncoding != "" || len(publishing.Body) < p.compressionThreshold {
+		return nil
+	}
+
+	start := time.Now()
+	compressed, err := compression.Compress(p.options.Compression, publishing.Body)
+	if err != nil {
+		return err
+	}
+	atomic.AddInt64(&p.stats.compressionTime, int64(time.Since(start)))
+
+	if len(compressed) >= len(publishing.Body) {
+		klog.V(5).Infof("Compression does not shrink %s, sending as is\n", publishing.MessageId)
+		return nil
+	}
+
+	atomic.AddUint64(&p.stats.compressed, 1)
+	atomic.AddUint64(&p.stats.uncompressedBytes, uint64(len(publishing.Body)))
+	atomic.AddUint64(&p.stats.compressedBytes, uint64(len(compressed)))
+
+	publishing.Body = compressed
+	publishing.ContentEncoding = compression.TypeToEncoding(p.options.Compression)
+
+	return nil
+}
+
+/*
+	Encrypts the body under a fresh data key. The key id and the wrapped data key
+	travel in the headers so the subscriber can pick the right key after a
+	rotation. Compression runs first as ciphertext does not compress, which also
+	settles the content-encoding the ciphertext is bound to.
+*/
+func (p *Publisher) encrypt(publishing *amqp.Publishing) error {
+	if p.options.KeyProvider == nil {
+		return nil
+	}
+
+	keyId, wrappedKey, ciphertext, err := encryption.Seal(*p.options.KeyProvider, publishing.ContentEncoding, publishing.Body)
+	if err != nil {
+		return err
+	}
+
+	headers := copyHeaders(publishing.Headers)
+	headers[encryption.HeaderAlgorithm] = encryption.AlgorithmAES256GCM
+	headers[encryption.HeaderKeyId] = keyId
+	headers[encryption.HeaderDataKey] = base64.StdEncoding.EncodeToString(wrappedKey)
+
+	publishing.Headers = headers
+	publishing.Body = ciphertext
+
+	atomic.AddUint64(&p.stats.encrypted, 1)
+
+	return nil
+}
+
+/*
+	Signs the final body along with the message id, timestamp, content type and
+	encoding and the configured headers. A claim check is always signed, its
+	digest pins the stored body, and so is the publish sequence. Signing last
+	means the subscriber can reject a forged message before fetching,
+	decrypting or decompressing anything.
+*/
+func (p *Publisher) sign(publishing *amqp.Publishing) error {
+	if p.options.Signer == nil {
+		return nil
+	}
+	signer := *p.options.Signer
+
+	// only headers the message actually carries are signed
+	names := make([]string, 0, len(p.options.SignedHeaders)+len(claimcheck.Headers)+1)
+	for _, name := range p.options.SignedHeaders {
+		if _, found := publishing.Headers[name]; found {
+			names = append(names, name)
+		}
+	}
+	if _, found := publishing.Headers[claimcheck.HeaderClaimCheck]; found {
+		names = append(names, claimcheck.Headers...)
+	}
+	if _, found := publishing.Headers[common.HeaderPublishSequence]; found {
+		names = append(names, common.HeaderPublishSequence)
+	}
+
+	data, err := signing.Canonical(&signing.Content{
+		MessageId:       publishing.MessageId,
+		Timestamp:       publishing.Timestamp,
+		ContentType:     publishing.ContentType,
+		ContentEncoding: publishing.ContentEncoding,
+		Headers:         publishing.Headers,
+		SignedHeaders:   names,
+		Body:            publishing.Body,
+	})
+	if err != nil {
+		return err
+	}
+	signature, err := signer.Sign(data)
+	if err != nil {
+		return err
+	}
+
+	headers := copyHeaders(publishing.Headers)
+	headers[signing.HeaderAlgorithm] = signer.Algorithm()
+	headers[signing.HeaderKeyId] = signer.KeyId()
+	headers[signing.HeaderSignedHeaders] = strings.Join(names, ",")
+	headers[signing.HeaderSignature] = base64.StdEncoding.EncodeToString(signature)
+
+	publishing.Headers = headers
+
+	atomic.AddUint64(&p.stats.signed, 1)
+
+	return nil
+}
+
+/*
+	Moves bodies over the threshold into the BlobStore and sends only the key,
+	size and digest. The stored body is exactly what the subscriber decrypts and
+	decompresses. A body whose message is never published is left for the store
+	to expire.
+*/
+func (p *Publisher) claimCheck(publishing *amqp.Publishing) error {
+	if p.options.BlobStore == nil || len(publishing.Body) < p.claimCheckThreshold {
+		return nil
+	}
+
+	key, err := common.NewMessageId()
+	if err != nil {
+		return err
+	}
+	err = (*p.options.BlobStore).Put(key, publishing.Body)
+	if err != nil {
+		return err
+	}
+
+	headers := copyHeaders(publishing.Headers)
+	headers[claimcheck.HeaderClaimCheck] = key
+	headers[claimcheck.HeaderClaimCheckSize] = int64(len(publishing.Body))
+	headers[claimcheck.HeaderClaimCheckDigest] = claimcheck.Digest(publishing.Body)
+
+	atomic.AddUint64(&p.stats.claimChecked, 1)
+	atomic.AddUint64(&p.stats.claimCheckedBytes, uint64(len(publishing.Body)))
+
+	publishing.Headers = headers
+	publishing.Body = nil
+
+	return nil
+}
@@ -4,18 +4,60 @@
 package publisher
 
 import (
+	"sync"
+	"time"
+
 	amqp "github.com/rabbitmq/amqp091-go"
 
 	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	ratelimit "github.com/dvonthenen/rabbitmq-manager/pkg/ratelimit"
+	spool "github.com/dvonthenen/rabbitmq-manager/pkg/spool"
 )
 
 type PublisherOptions struct {
 	*interfaces.PublisherOptions
 
 	Channel *amqp.Channel
+
+	// opens the pooled channels SendMessages spreads a batch over
+	OpenChannel func() (*amqp.Channel, error)
 }
 
 type Publisher struct {
-	options PublisherOptions
-	channel *amqp.Channel
+	options   PublisherOptions
+	channel   *amqp.Channel
+	publishMu sync.Mutex
+
+	// confirms and returns
+	notifyChannel  *amqp.Channel
+	confirmTimeout time.Duration
+	pending        map[ledgerKey]*confirmation
+	pendingMu      sync.Mutex
+
+	// at-least-once
+	sequence    uint64
+	ledgerSlots chan struct{}
+
+	// returns
+	returnSequence uint64
+
+	// channel pool
+	pool   []*pooledChannel
+	poolMu sync.Mutex
+
+	// spool
+	spool     *spool.Spool
+	spoolMu   sync.Mutex
+	replaying int32
+
+	// rate limiting
+	limiter *ratelimit.Throttle
+
+	// compression
+	compressionThreshold int
+
+	// claim check
+	claimCheckThreshold int
+
+	stats stats
 }
@@ -0,0 +1,13 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"errors"
+)
+
+var (
+	// ErrInvalidLimit the rate and burst cannot be negative
+	ErrInvalidLimit = errors.New("the rate and burst cannot be negative")
+)
@@ -0,0 +1,128 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"time"
+)
+
+func New(rate float64, burst int) (*Limiter, error) {
+	l := &Limiter{}
+
+	err := l.SetLimit(rate, burst)
+	if err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+/*
+	Changes the rate and burst at runtime. A zero burst allows one second worth of
+	tokens. The bucket starts out full, later changes keep the tokens earned so far
+	up to the new burst.
+*/
+func (l *Limiter) SetLimit(rate float64, burst int) error {
+	if rate < 0 || burst < 0 {
+		return ErrInvalidLimit
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	full := l.rate <= 0 || l.last.IsZero()
+	if !full {
+		l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	}
+
+	l.rate = rate
+	l.burst = float64(burst)
+	if l.burst == 0 {
+		l.burst = math.Max(1, math.Ceil(rate))
+	}
+	if full {
+		l.tokens = l.burst
+	} else {
+		l.tokens = math.Min(l.burst, l.tokens)
+	}
+	l.last = now
+
+	return nil
+}
+
+func (l *Limiter) Limited() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.rate > 0
+}
+
+/*
+	Takes n tokens if they are available now, otherwise returns how long until
+	they are. Requests larger than the burst only wait for a full bucket and then
+	go into debt, so a single oversized message is never stuck forever.
+*/
+func (l *Limiter) reserve(n int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.rate <= 0 {
+		return 0
+	}
+
+	now := time.Now()
+	l.tokens = math.Min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+	l.last = now
+
+	need := math.Min(float64(n), l.burst)
+	if l.tokens >= need {
+		l.tokens -= float64(n)
+		return 0
+	}
+
+	return time.Duration((need - l.tokens) / l.rate * float64(time.Second))
+}
+
+/*
+	Takes n tokens without waiting, reporting whether that was possible
+*/
+func (l *Limiter) Allow(n int) bool {
+	return l.reserve(n) == 0
+}
+
+/*
+	Gives back tokens taken by a request that did not go ahead
+*/
+func (l *Limiter) refund(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.tokens = math.Min(l.burst, l.tokens+float64(n))
+}
+
+/*
+	Blocks until n tokens are available or ctx is done, returning how long it
+	waited
+*/
+func (l *Limiter) Wait(ctx context.Context, n int) (time.Duration, error) {
+	start := time.Now()
+
+	for {
+		delay := l.reserve(n)
+		if delay == 0 {
+			return time.Since(start), nil
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return time.Since(start), ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,204 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+// slack for the time that passes between two calls in a test
+const tolerance = 20 * time.Millisecond
+
+func within(got, want time.Duration) bool {
+	return got >= want-tolerance && got <= want+tolerance
+}
+
+func TestNewValidation(t *testing.T) {
+	tests := []struct {
+		name  string
+		rate  float64
+		burst int
+		err   error
+	}{
+		{"unlimited", 0, 0, nil},
+		{"rate and burst", 10, 5, nil},
+		{"negative rate", -1, 0, ErrInvalidLimit},
+		{"negative burst", 1, -1, ErrInvalidLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.rate, tt.burst)
+			if err != tt.err {
+				t.Fatalf("New() err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestReserve(t *testing.T) {
+	tests := []struct {
+		name   string
+		rate   float64
+		burst  int
+		takes  []int
+		delays []time.Duration
+	}{
+		{"unlimited", 0, 0, []int{1000, 1000}, []time.Duration{0, 0}},
+		{"burst then refill rate", 2, 2, []int{1, 1, 1}, []time.Duration{0, 0, 500 * time.Millisecond}},
+		{"zero burst is one second of tokens", 4, 0, []int{4, 1}, []time.Duration{0, 250 * time.Millisecond}},
+		{"zero burst below one token per second", 0.5, 0, []int{1, 1}, []time.Duration{0, 2 * time.Second}},
+		{"oversized waits for a full bucket", 10, 5, []int{3, 20}, []time.Duration{0, 300 * time.Millisecond}},
+		{"oversized goes into debt", 10, 5, []int{20, 1}, []time.Duration{0, 1600 * time.Millisecond}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := New(tt.rate, tt.burst)
+			if err != nil {
+				t.Fatalf("New failed. Err: %v", err)
+			}
+			for i, n := range tt.takes {
+				delay := l.reserve(n)
+				if !within(delay, tt.delays[i]) {
+					t.Fatalf("take %d of %d waits %v, want %v", i, n, delay, tt.delays[i])
+				}
+			}
+		})
+	}
+}
+
+func TestSetLimitKeepsTokens(t *testing.T) {
+	tests := []struct {
+		name   string
+		burst  int
+		taken  int
+		rate   float64
+		limit  int
+		tokens float64
+	}{
+		{"raised burst does not refill", 10, 8, 10, 100, 2},
+		{"lowered burst clamps", 10, 2, 10, 5, 5},
+		{"debt is kept", 10, 15, 10, 10, -5},
+		{"new rate keeps tokens", 10, 10, 1000, 10, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l, err := New(1, tt.burst)
+			if err != nil {
+				t.Fatalf("New failed. Err: %v", err)
+			}
+			l.reserve(tt.taken)
+
+			err = l.SetLimit(tt.rate, tt.limit)
+			if err != nil {
+				t.Fatalf("SetLimit failed. Err: %v", err)
+			}
+			// at most a few milliseconds at one token per second have been earned
+			if l.tokens < tt.tokens || l.tokens > tt.tokens+0.1 {
+				t.Fatalf("tokens = %v, want %v", l.tokens, tt.tokens)
+			}
+		})
+	}
+}
+
+func TestSetLimitFromUnlimitedStartsFull(t *testing.T) {
+	l, err := New(0, 0)
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+	l.reserve(100)
+
+	err = l.SetLimit(10, 5)
+	if err != nil {
+		t.Fatalf("SetLimit failed. Err: %v", err)
+	}
+	for i := 0; i < 5; i++ {
+		if !l.Allow(1) {
+			t.Fatalf("take %d not allowed from a full bucket", i)
+		}
+	}
+	if l.Allow(1) {
+		t.Fatalf("allowed past the burst")
+	}
+}
+
+func TestWait(t *testing.T) {
+	l, err := New(20, 1)
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+
+	waited, err := l.Wait(context.Background(), 1)
+	if err != nil || waited > tolerance {
+		t.Fatalf("first Wait waited %v. Err: %v", waited, err)
+	}
+	waited, err = l.Wait(context.Background(), 1)
+	if err != nil || !within(waited, 50*time.Millisecond) {
+		t.Fatalf("second Wait waited %v, want 50ms. Err: %v", waited, err)
+	}
+
+	// a cancelled wait does not take the tokens
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = l.Wait(ctx, 1)
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Wait() err = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if l.tokens < 0 {
+		t.Fatalf("cancelled Wait took tokens")
+	}
+}
+
+func TestThrottleAllowRefunds(t *testing.T) {
+	throttle, err := NewThrottle(interfaces.RateLimit{
+		MessagesPerSecond: 1,
+		MessageBurst:      2,
+		BytesPerSecond:    1,
+		ByteBurst:         10,
+	})
+	if err != nil {
+		t.Fatalf("NewThrottle failed. Err: %v", err)
+	}
+
+	if !throttle.Allow(8) {
+		t.Fatalf("first message not allowed")
+	}
+	if throttle.Allow(5) {
+		t.Fatalf("allowed a message over the remaining bytes")
+	}
+	// the message token was refunded when the bytes did not fit
+	if !throttle.Allow(2) {
+		t.Fatalf("message token was not refunded")
+	}
+	if throttle.Allow(0) {
+		t.Fatalf("allowed past the message burst")
+	}
+}
+
+func TestThrottleValidation(t *testing.T) {
+	_, err := NewThrottle(interfaces.RateLimit{MessagesPerSecond: -1})
+	if err != ErrInvalidLimit {
+		t.Fatalf("NewThrottle() err = %v, want %v", err, ErrInvalidLimit)
+	}
+
+	throttle, err := NewThrottle(interfaces.RateLimit{})
+	if err != nil {
+		t.Fatalf("NewThrottle failed. Err: %v", err)
+	}
+	if throttle.Limited() {
+		t.Fatalf("zero limit is limited")
+	}
+	if err := throttle.SetLimit(interfaces.RateLimit{ByteBurst: -1}); err != ErrInvalidLimit {
+		t.Fatalf("SetLimit() err = %v, want %v", err, ErrInvalidLimit)
+	}
+	if err := throttle.SetLimit(interfaces.RateLimit{BytesPerSecond: 1}); err != nil || !throttle.Limited() {
+		t.Fatalf("SetLimit did not apply. Err: %v", err)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"context"
+	"time"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+func NewThrottle(limit interfaces.RateLimit) (*Throttle, error) {
+	messages, err := New(limit.MessagesPerSecond, limit.MessageBurst)
+	if err != nil {
+		return nil, err
+	}
+	bytes, err := New(limit.BytesPerSecond, limit.ByteBurst)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &Throttle{
+		messages: messages,
+		bytes:    bytes,
+	}
+	return t, nil
+}
+
+func ValidateLimit(limit interfaces.RateLimit) error {
+	if limit.MessagesPerSecond < 0 || limit.MessageBurst < 0 || limit.BytesPerSecond < 0 || limit.ByteBurst < 0 {
+		return ErrInvalidLimit
+	}
+	return nil
+}
+
+func (t *Throttle) SetLimit(limit interfaces.RateLimit) error {
+	err := ValidateLimit(limit)
+	if err != nil {
+		return err
+	}
+
+	t.messages.SetLimit(limit.MessagesPerSecond, limit.MessageBurst)
+	t.bytes.SetLimit(limit.BytesPerSecond, limit.ByteBurst)
+
+	return nil
+}
+
+func (t *Throttle) Limited() bool {
+	return t.messages.Limited() || t.bytes.Limited()
+}
+
+/*
+	Takes one message of the given size if both limits allow it right now
+*/
+func (t *Throttle) Allow(size int) bool {
+	if !t.messages.Allow(1) {
+		return false
+	}
+	if !t.bytes.Allow(size) {
+		t.messages.refund(1)
+		return false
+	}
+	return true
+}
+
+/*
+	Blocks until one message of the given size fits within both limits, returning
+	how long it waited
+*/
+func (t *Throttle) Wait(ctx context.Context, size int) (time.Duration, error) {
+	waited, err := t.messages.Wait(ctx, 1)
+	if err != nil {
+		return waited, err
+	}
+
+	bytesWaited, err := t.bytes.Wait(ctx, size)
+	return waited + bytesWaited, err
+}
@@ -0,0 +1,29 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package ratelimit
+
+import (
+	"sync"
+	"time"
+)
+
+/*
+	Token bucket refilled at rate tokens per second and holding up to burst
+	tokens. A rate of zero disables the limit.
+*/
+type Limiter struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	mu     sync.Mutex
+}
+
+/*
+	Message and byte limits applied together
+*/
+type Throttle struct {
+	messages *Limiter
+	bytes    *Limiter
+}
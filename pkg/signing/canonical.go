@@ -0,0 +1,169 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package signing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+/*
+	Checks the names of the headers to sign
+*/
+func ValidateHeaderNames(names []string) error {
+	for _, name := range names {
+		if name == "" || strings.Contains(name, ",") || strings.HasPrefix(name, HeaderSignature) {
+			return ErrInvalidHeaderName
+		}
+	}
+	return nil
+}
+
+/*
+	Builds the byte string a signature is made over. Every field is length
+	prefixed so no two messages share a canonical form. The timestamp is taken in
+	seconds as that is all AMQP carries.
+*/
+func Canonical(content *Content) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var timestamp int64
+	if !content.Timestamp.IsZero() {
+		timestamp = content.Timestamp.Unix()
+	}
+
+	writeBytes(&buf, []byte(content.MessageId))
+	writeUint64(&buf, uint64(timestamp))
+	writeBytes(&buf, []byte(content.ContentType))
+	writeBytes(&buf, []byte(content.ContentEncoding))
+
+	for _, name := range content.SignedHeaders {
+		value, found := content.Headers[name]
+		if !found {
+			return nil, ErrMissingHeader
+		}
+		writeBytes(&buf, []byte(name))
+		err := writeValue(&buf, value)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	writeBytes(&buf, content.Body)
+
+	return buf.Bytes(), nil
+}
+
+func writeBytes(buf *bytes.Buffer, data []byte) {
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(data)))
+	buf.Write(size[:])
+	buf.Write(data)
+}
+
+func writeUint64(buf *bytes.Buffer, value uint64) {
+	var data [8]byte
+	binary.BigEndian.PutUint64(data[:], value)
+	buf.Write(data[:])
+}
+
+func writeUint32(buf *bytes.Buffer, value uint32) {
+	var data [4]byte
+	binary.BigEndian.PutUint32(data[:], value)
+	buf.Write(data[:])
+}
+
+/*
+	Writes a header value tagged with its AMQP field type. Values are normalized
+	to what the subscriber decodes, exactly as amqp091 puts them on the wire: an
+	int is sent as a 32 bit integer and a time in whole seconds. Tables are
+	written in key order since the wire order is random.
+*/
+func writeValue(buf *bytes.Buffer, value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		buf.WriteByte('V')
+	case bool:
+		buf.WriteByte('t')
+		if v {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	case byte:
+		buf.WriteByte('B')
+		buf.WriteByte(v)
+	case int8:
+		buf.WriteByte('b')
+		buf.WriteByte(byte(v))
+	case int16:
+		var data [2]byte
+		binary.BigEndian.PutUint16(data[:], uint16(v))
+		buf.WriteByte('s')
+		buf.Write(data[:])
+	case int:
+		buf.WriteByte('I')
+		writeUint32(buf, uint32(v))
+	case int32:
+		buf.WriteByte('I')
+		writeUint32(buf, uint32(v))
+	case int64:
+		buf.WriteByte('l')
+		writeUint64(buf, uint64(v))
+	case float32:
+		buf.WriteByte('f')
+		writeUint32(buf, math.Float32bits(v))
+	case float64:
+		buf.WriteByte('d')
+		writeUint64(buf, math.Float64bits(v))
+	case amqp.Decimal:
+		buf.WriteByte('D')
+		buf.WriteByte(v.Scale)
+		writeUint32(buf, uint32(v.Value))
+	case string:
+		buf.WriteByte('S')
+		writeBytes(buf, []byte(v))
+	case []byte:
+		buf.WriteByte('x')
+		writeBytes(buf, v)
+	case time.Time:
+		buf.WriteByte('T')
+		writeUint64(buf, uint64(v.Unix()))
+	case []interface{}:
+		buf.WriteByte('A')
+		writeUint32(buf, uint32(len(v)))
+		for _, item := range v {
+			err := writeValue(buf, item)
+			if err != nil {
+				return err
+			}
+		}
+	case amqp.Table:
+		buf.WriteByte('F')
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		writeUint32(buf, uint32(len(keys)))
+		for _, key := range keys {
+			writeBytes(buf, []byte(key))
+			err := writeValue(buf, v[key])
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return ErrUnsupportedHeaderValue
+	}
+
+	return nil
+}
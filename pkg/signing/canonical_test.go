@@ -0,0 +1,186 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package signing
+
+import (
+	"bytes"
+	"math"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func canonicalHeaders(t *testing.T, headers amqp.Table, names []string) []byte {
+	t.Helper()
+
+	data, err := Canonical(&Content{
+		MessageId:     "id",
+		Headers:       headers,
+		SignedHeaders: names,
+		Body:          []byte("body"),
+	})
+	if err != nil {
+		t.Fatalf("Canonical failed. Err: %v", err)
+	}
+	return data
+}
+
+func TestCanonicalSurvivesWire(t *testing.T) {
+	zone := time.FixedZone("test", 3*60*60)
+
+	tests := []struct {
+		name  string
+		value interface{}
+	}{
+		{"nil", nil},
+		{"bool", true},
+		{"byte", byte(7)},
+		{"int8", int8(-7)},
+		{"int16", int16(-300)},
+		{"int", 42},
+		{"int above int32 range", math.MaxInt32 + 10},
+		{"int32", int32(math.MinInt32)},
+		{"int64", int64(math.MaxInt64)},
+		{"float32", float32(1.5)},
+		{"float64", math.Pi},
+		{"decimal", amqp.Decimal{Scale: 2, Value: 12345}},
+		{"string", "value"},
+		{"bytes", []byte{0, 1, 2}},
+		{"time with nanoseconds and zone", time.Now().In(zone)},
+		{"array", []interface{}{int32(1), "two", []interface{}{true}}},
+		{"table", amqp.Table{"b": int32(2), "a": "one", "c": amqp.Table{"d": time.Now()}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sent := amqp.Table{"h": tt.value}
+			received := wireRoundTrip(t, sent)
+
+			if _, found := received["h"]; !found {
+				t.Fatalf("header lost on the wire: %v", received)
+			}
+			if !bytes.Equal(canonicalHeaders(t, sent, []string{"h"}), canonicalHeaders(t, received, []string{"h"})) {
+				t.Fatalf("canonical form changed on the wire: sent %#v received %#v", tt.value, received["h"])
+			}
+		})
+	}
+}
+
+func TestCanonicalDistinguishesTypes(t *testing.T) {
+	tests := []struct {
+		name string
+		a    interface{}
+		b    interface{}
+	}{
+		{"int32 and string", int32(5), "5"},
+		{"int32 and int64", int32(5), int64(5)},
+		{"string and bytes", "abc", []byte("abc")},
+		{"bool and byte", true, byte(1)},
+		{"nil and empty string", nil, ""},
+		{"float32 and float64", float32(1), float64(1)},
+		{"nested tables", amqp.Table{"a": "b"}, amqp.Table{"a": "c"}},
+		{"array and table", []interface{}{}, amqp.Table{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := canonicalHeaders(t, amqp.Table{"h": tt.a}, []string{"h"})
+			b := canonicalHeaders(t, amqp.Table{"h": tt.b}, []string{"h"})
+			if bytes.Equal(a, b) {
+				t.Fatalf("%#v and %#v share a canonical form", tt.a, tt.b)
+			}
+		})
+	}
+}
+
+func TestCanonicalFields(t *testing.T) {
+	base := Content{
+		MessageId:       "id",
+		Timestamp:       time.Unix(1700000000, 0),
+		ContentType:     "application/json",
+		ContentEncoding: "gzip",
+		Headers:         amqp.Table{"a": "1", "b": "2"},
+		SignedHeaders:   []string{"a"},
+		Body:            []byte("body"),
+	}
+
+	tests := []struct {
+		name    string
+		change  func(c *Content)
+		differs bool
+	}{
+		{"message id", func(c *Content) { c.MessageId = "other" }, true},
+		{"timestamp", func(c *Content) { c.Timestamp = c.Timestamp.Add(time.Second) }, true},
+		{"sub second timestamp", func(c *Content) { c.Timestamp = c.Timestamp.Add(time.Millisecond) }, false},
+		{"content type", func(c *Content) { c.ContentType = "text/plain" }, true},
+		{"content encoding", func(c *Content) { c.ContentEncoding = "" }, true},
+		{"signed header", func(c *Content) { c.Headers = amqp.Table{"a": "x", "b": "2"} }, true},
+		{"unsigned header", func(c *Content) { c.Headers = amqp.Table{"a": "1", "b": "x"} }, false},
+		{"body", func(c *Content) { c.Body = []byte("other") }, true},
+		{"body moved into the encoding", func(c *Content) { c.ContentEncoding = "gzipbody"; c.Body = nil }, true},
+	}
+
+	want, err := Canonical(&base)
+	if err != nil {
+		t.Fatalf("Canonical failed. Err: %v", err)
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			changed := base
+			tt.change(&changed)
+
+			got, err := Canonical(&changed)
+			if err != nil {
+				t.Fatalf("Canonical failed. Err: %v", err)
+			}
+			if !bytes.Equal(want, got) != tt.differs {
+				t.Fatalf("canonical form differs = %v, want %v", !bytes.Equal(want, got), tt.differs)
+			}
+		})
+	}
+}
+
+func TestCanonicalErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		headers amqp.Table
+		err     error
+	}{
+		{"missing header", amqp.Table{}, ErrMissingHeader},
+		{"unsupported value", amqp.Table{"h": struct{}{}}, ErrUnsupportedHeaderValue},
+		{"unsupported value in a table", amqp.Table{"h": amqp.Table{"x": uint64(1)}}, ErrUnsupportedHeaderValue},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Canonical(&Content{Headers: tt.headers, SignedHeaders: []string{"h"}})
+			if err != tt.err {
+				t.Fatalf("Canonical() err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestValidateHeaderNames(t *testing.T) {
+	tests := []struct {
+		name  string
+		names []string
+		err   error
+	}{
+		{"valid", []string{"a", "x-tenant"}, nil},
+		{"empty", []string{""}, ErrInvalidHeaderName},
+		{"comma", []string{"a,b"}, ErrInvalidHeaderName},
+		{"signature header", []string{HeaderSignature}, ErrInvalidHeaderName},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ValidateHeaderNames(tt.names); err != tt.err {
+				t.Fatalf("ValidateHeaderNames() err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,106 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package signing
+
+import (
+	"errors"
+	"fmt"
+)
+
+const (
+	// HeaderSignature base64 signature over the canonical form of the message
+	HeaderSignature string = "x-signature"
+
+	// HeaderAlgorithm the algorithm the signature was made with
+	HeaderAlgorithm string = "x-signature-algorithm"
+
+	// HeaderKeyId id of the key the signature was made with
+	HeaderKeyId string = "x-signature-key-id"
+
+	// HeaderSignedHeaders comma separated names of the headers covered by the signature
+	HeaderSignedHeaders string = "x-signature-headers"
+
+	// AlgorithmHMACSHA256 HMAC-SHA256 with a shared secret
+	AlgorithmHMACSHA256 string = "hmac-sha256"
+
+	// AlgorithmEd25519 Ed25519 with a private key, verified with the public key
+	AlgorithmEd25519 string = "ed25519"
+
+	// DefaultReplayCacheSize number of message ids remembered within the replay window
+	DefaultReplayCacheSize int = 100000
+
+	minHMACKeySize int = 32
+)
+
+var (
+	// ErrInvalidKeyId the key id is empty
+	ErrInvalidKeyId = errors.New("the key id is empty")
+
+	// ErrInvalidKeySize HMAC keys must be at least 32 bytes, Ed25519 keys must have the standard size
+	ErrInvalidKeySize = errors.New("HMAC keys must be at least 32 bytes, Ed25519 keys must have the standard size")
+
+	// ErrUnknownKey the key id is not known to the verifier
+	ErrUnknownKey = errors.New("the key id is not known to the verifier")
+
+	// ErrUnknownAlgorithm the message is signed with an unsupported algorithm
+	ErrUnknownAlgorithm = errors.New("the message is signed with an unsupported algorithm")
+
+	// ErrInvalidHeaderName signed header names cannot be empty, contain a comma or be a signature header
+	ErrInvalidHeaderName = errors.New("signed header names cannot be empty, contain a comma or be a signature header")
+
+	// ErrMissingHeader a signed header is missing from the message
+	ErrMissingHeader = errors.New("a signed header is missing from the message")
+
+	// ErrUnsupportedHeaderValue a signed header holds a value AMQP cannot carry
+	ErrUnsupportedHeaderValue = errors.New("a signed header holds a value AMQP cannot carry")
+
+	// ErrMissingSignature the message is not signed
+	ErrMissingSignature = errors.New("the message is not signed")
+
+	// ErrMalformedSignature the signature headers are malformed
+	ErrMalformedSignature = errors.New("the signature headers are malformed")
+
+	// ErrInvalidSignature the signature does not match the message
+	ErrInvalidSignature = errors.New("the signature does not match the message")
+
+	// ErrMissingTimestamp the message has no timestamp to check the replay window against
+	ErrMissingTimestamp = errors.New("the message has no timestamp to check the replay window against")
+
+	// ErrOutsideReplayWindow the message timestamp is outside the replay window
+	ErrOutsideReplayWindow = errors.New("the message timestamp is outside the replay window")
+
+	// ErrUnsignedHeader a header the subscriber requires to be signed is not signed
+	ErrUnsignedHeader = errors.New("a header the subscriber requires to be signed is not signed")
+
+	// ErrMissingMessageId the message has no id to detect a replay by
+	ErrMissingMessageId = errors.New("the message has no id to detect a replay by")
+
+	// ErrReplayed the message was already received within the replay window
+	ErrReplayed = errors.New("the message was already received within the replay window")
+
+	// ErrVerificationFailed the message could not be verified
+	ErrVerificationFailed = errors.New("the message could not be verified")
+)
+
+/*
+	Returned when a message fails verification, matches ErrVerificationFailed and
+	unwraps to the cause
+*/
+type VerificationError struct {
+	KeyId     string
+	MessageId string
+	Err       error
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("%v (key: %s, id: %s): %v", ErrVerificationFailed, e.KeyId, e.MessageId, e.Err)
+}
+
+func (e *VerificationError) Is(target error) bool {
+	return target == ErrVerificationFailed
+}
+
+func (e *VerificationError) Unwrap() error {
+	return e.Err
+}
@@ -0,0 +1,77 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package signing
+
+import (
+	"container/list"
+	"time"
+)
+
+/*
+	Remembers message ids for as long as their timestamp is inside the window. A
+	zero size uses DefaultReplayCacheSize.
+*/
+func NewReplayCache(window time.Duration, size int) *ReplayCache {
+	if size == 0 {
+		size = DefaultReplayCacheSize
+	}
+
+	return &ReplayCache{
+		window:  window,
+		size:    size,
+		entries: make(map[string]*list.Element),
+		order:   list.New(),
+	}
+}
+
+/*
+	Records a message id and fails with ErrReplayed when it was already seen.
+	When the cache is full the oldest id is forgotten early, so the size must
+	cover the number of messages expected within the window.
+*/
+func (c *ReplayCache) Check(id string, timestamp time.Time) error {
+	if id == "" {
+		return ErrMissingMessageId
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	for front := c.order.Front(); front != nil; front = c.order.Front() {
+		entry := front.Value.(*replayEntry)
+		if entry.expires.After(now) {
+			break
+		}
+		c.remove(front)
+	}
+
+	if _, found := c.entries[id]; found {
+		return ErrReplayed
+	}
+
+	if c.order.Len() >= c.size {
+		c.remove(c.order.Front())
+	}
+
+	// nothing is accepted once its timestamp leaves the window
+	c.entries[id] = c.order.PushBack(&replayEntry{
+		id:      id,
+		expires: timestamp.Add(c.window),
+	})
+
+	return nil
+}
+
+func (c *ReplayCache) remove(element *list.Element) {
+	c.order.Remove(element)
+	delete(c.entries, element.Value.(*replayEntry).id)
+}
+
+func (c *ReplayCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
@@ -0,0 +1,89 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package signing
+
+import (
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestReplayCache(t *testing.T) {
+	now := time.Now()
+
+	type check struct {
+		id        string
+		timestamp time.Time
+		err       error
+	}
+
+	tests := []struct {
+		name   string
+		size   int
+		checks []check
+	}{
+		{
+			name: "first delivery accepted",
+			checks: []check{
+				{"a", now, nil},
+				{"b", now, nil},
+			},
+		},
+		{
+			name: "replay within window rejected",
+			checks: []check{
+				{"a", now, nil},
+				{"a", now, ErrReplayed},
+				{"a", now.Add(time.Second), ErrReplayed},
+			},
+		},
+		{
+			name: "expired ids forgotten",
+			checks: []check{
+				{"a", now.Add(-2 * time.Minute), nil},
+				{"a", now, nil},
+			},
+		},
+		{
+			name: "missing id rejected",
+			checks: []check{
+				{"", now, ErrMissingMessageId},
+			},
+		},
+		{
+			name: "oldest id forgotten when full",
+			size: 2,
+			checks: []check{
+				{"a", now, nil},
+				{"b", now, nil},
+				{"c", now, nil},
+				{"a", now, nil},
+				{"c", now, ErrReplayed},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cache := NewReplayCache(time.Minute, tt.size)
+			for i, c := range tt.checks {
+				if err := cache.Check(c.id, c.timestamp); err != c.err {
+					t.Fatalf("check %d (%s) err = %v, want %v", i, c.id, err, c.err)
+				}
+			}
+		})
+	}
+}
+
+func TestReplayCacheBounded(t *testing.T) {
+	cache := NewReplayCache(time.Hour, 100)
+	for i := 0; i < 1000; i++ {
+		if err := cache.Check(fmt.Sprintf("id-%d", i), time.Now()); err != nil {
+			t.Fatalf("Check failed. Err: %v", err)
+		}
+	}
+	if cache.Len() != 100 {
+		t.Fatalf("cache holds %d ids, want 100", cache.Len())
+	}
+}
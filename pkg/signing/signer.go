@@ -0,0 +1,68 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+)
+
+func NewHMACSigner(keyId string, key []byte) (*HMACSigner, error) {
+	if keyId == "" {
+		return nil, ErrInvalidKeyId
+	}
+	if len(key) < minHMACKeySize {
+		return nil, ErrInvalidKeySize
+	}
+
+	return &HMACSigner{
+		keyId: keyId,
+		key:   append([]byte(nil), key...),
+	}, nil
+}
+
+func (s *HMACSigner) Algorithm() string {
+	return AlgorithmHMACSHA256
+}
+
+func (s *HMACSigner) KeyId() string {
+	return s.keyId
+}
+
+func (s *HMACSigner) Sign(data []byte) ([]byte, error) {
+	return signHMAC(s.key, data), nil
+}
+
+func NewEd25519Signer(keyId string, key ed25519.PrivateKey) (*Ed25519Signer, error) {
+	if keyId == "" {
+		return nil, ErrInvalidKeyId
+	}
+	if len(key) != ed25519.PrivateKeySize {
+		return nil, ErrInvalidKeySize
+	}
+
+	return &Ed25519Signer{
+		keyId: keyId,
+		key:   key,
+	}, nil
+}
+
+func (s *Ed25519Signer) Algorithm() string {
+	return AlgorithmEd25519
+}
+
+func (s *Ed25519Signer) KeyId() string {
+	return s.keyId
+}
+
+func (s *Ed25519Signer) Sign(data []byte) ([]byte, error) {
+	return ed25519.Sign(s.key, data), nil
+}
+
+func signHMAC(key, data []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
@@ -0,0 +1,147 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package signing
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"errors"
+	"testing"
+)
+
+func TestSignVerify(t *testing.T) {
+	hmacKey := bytes.Repeat([]byte{1}, 32)
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherPublic, _, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hmacSigner, err := NewHMACSigner("hmac-1", hmacKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed25519Signer, err := NewEd25519Signer("ed-1", private)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := NewKeyVerifier()
+	if err := verifier.AddHMACKey("hmac-1", hmacKey); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.AddHMACKey("hmac-2", bytes.Repeat([]byte{2}, 32)); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.AddEd25519Key("ed-1", public); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifier.AddEd25519Key("ed-2", otherPublic); err != nil {
+		t.Fatal(err)
+	}
+
+	data := []byte("canonical")
+
+	tests := []struct {
+		name      string
+		signer    interface{ Sign([]byte) ([]byte, error) }
+		algorithm string
+		keyId     string
+		data      []byte
+		err       error
+	}{
+		{"hmac", hmacSigner, AlgorithmHMACSHA256, "hmac-1", data, nil},
+		{"ed25519", ed25519Signer, AlgorithmEd25519, "ed-1", data, nil},
+		{"hmac tampered", hmacSigner, AlgorithmHMACSHA256, "hmac-1", []byte("tampered"), ErrInvalidSignature},
+		{"ed25519 tampered", ed25519Signer, AlgorithmEd25519, "ed-1", []byte("tampered"), ErrInvalidSignature},
+		{"hmac wrong key", hmacSigner, AlgorithmHMACSHA256, "hmac-2", data, ErrInvalidSignature},
+		{"ed25519 wrong key", ed25519Signer, AlgorithmEd25519, "ed-2", data, ErrInvalidSignature},
+		{"unknown key", hmacSigner, AlgorithmHMACSHA256, "missing", data, ErrUnknownKey},
+		{"ed25519 key used as hmac secret", hmacSigner, AlgorithmHMACSHA256, "ed-1", data, ErrUnknownKey},
+		{"hmac key used as ed25519 key", ed25519Signer, AlgorithmEd25519, "hmac-1", data, ErrUnknownKey},
+		{"unknown algorithm", hmacSigner, "none", "hmac-1", data, ErrUnknownAlgorithm},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signature, err := tt.signer.Sign(data)
+			if err != nil {
+				t.Fatalf("Sign failed. Err: %v", err)
+			}
+
+			err = verifier.Verify(tt.algorithm, tt.keyId, tt.data, signature)
+			if err != tt.err {
+				t.Fatalf("Verify() err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestRemoveKey(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	signer, err := NewHMACSigner("k", key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := NewKeyVerifier()
+	if err := verifier.AddHMACKey("k", key); err != nil {
+		t.Fatal(err)
+	}
+
+	signature, _ := signer.Sign([]byte("data"))
+	verifier.RemoveKey("k")
+
+	err = verifier.Verify(AlgorithmHMACSHA256, "k", []byte("data"), signature)
+	if err != ErrUnknownKey {
+		t.Fatalf("Verify() err = %v, want %v", err, ErrUnknownKey)
+	}
+}
+
+func TestKeyValidation(t *testing.T) {
+	public, private, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := NewKeyVerifier()
+
+	tests := []struct {
+		name string
+		fn   func() error
+		err  error
+	}{
+		{"hmac signer without id", func() error { _, err := NewHMACSigner("", make([]byte, 32)); return err }, ErrInvalidKeyId},
+		{"hmac signer short key", func() error { _, err := NewHMACSigner("k", make([]byte, 16)); return err }, ErrInvalidKeySize},
+		{"ed25519 signer without id", func() error { _, err := NewEd25519Signer("", private); return err }, ErrInvalidKeyId},
+		{"ed25519 signer bad key", func() error { _, err := NewEd25519Signer("k", private[:10]); return err }, ErrInvalidKeySize},
+		{"hmac verifier short key", func() error { return verifier.AddHMACKey("k", make([]byte, 31)) }, ErrInvalidKeySize},
+		{"ed25519 verifier bad key", func() error { return verifier.AddEd25519Key("k", public[:10]) }, ErrInvalidKeySize},
+		{"ed25519 verifier without id", func() error { return verifier.AddEd25519Key("", public) }, ErrInvalidKeyId},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.fn(); err != tt.err {
+				t.Fatalf("err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVerificationError(t *testing.T) {
+	err := error(&VerificationError{KeyId: "k", MessageId: "m", Err: ErrInvalidSignature})
+
+	if !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("does not match ErrVerificationFailed")
+	}
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Fatalf("does not unwrap to the cause")
+	}
+	var verr *VerificationError
+	if !errors.As(err, &verr) || verr.KeyId != "k" {
+		t.Fatalf("errors.As failed")
+	}
+}
@@ -0,0 +1,70 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package signing
+
+import (
+	"container/list"
+	"crypto/ed25519"
+	"sync"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+/*
+	The parts of a message covered by a signature. Only the headers named in
+	SignedHeaders are included, in that order.
+*/
+type Content struct {
+	MessageId       string
+	Timestamp       time.Time
+	ContentType     string
+	ContentEncoding string
+	Headers         amqp.Table
+	SignedHeaders   []string
+	Body            []byte
+}
+
+/*
+	Signs with a shared HMAC-SHA256 secret
+*/
+type HMACSigner struct {
+	keyId string
+	key   []byte
+}
+
+/*
+	Signs with an Ed25519 private key
+*/
+type Ed25519Signer struct {
+	keyId string
+	key   ed25519.PrivateKey
+}
+
+/*
+	Verifies signatures with HMAC secrets and Ed25519 public keys by key id. Keys
+	can be added ahead of a rotation and removed once no message uses them.
+*/
+type KeyVerifier struct {
+	hmacKeys    map[string][]byte
+	ed25519Keys map[string]ed25519.PublicKey
+	mu          sync.RWMutex
+}
+
+/*
+	Bounded set of recently seen message ids, used to reject a signed message
+	that is published again within its replay window
+*/
+type ReplayCache struct {
+	window  time.Duration
+	size    int
+	entries map[string]*list.Element
+	order   *list.List
+	mu      sync.Mutex
+}
+
+type replayEntry struct {
+	id      string
+	expires time.Time
+}
@@ -0,0 +1,86 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package signing
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+)
+
+func NewKeyVerifier() *KeyVerifier {
+	return &KeyVerifier{
+		hmacKeys:    make(map[string][]byte),
+		ed25519Keys: make(map[string]ed25519.PublicKey),
+	}
+}
+
+func (v *KeyVerifier) AddHMACKey(keyId string, key []byte) error {
+	if keyId == "" {
+		return ErrInvalidKeyId
+	}
+	if len(key) < minHMACKeySize {
+		return ErrInvalidKeySize
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.hmacKeys[keyId] = append([]byte(nil), key...)
+	return nil
+}
+
+func (v *KeyVerifier) AddEd25519Key(keyId string, key ed25519.PublicKey) error {
+	if keyId == "" {
+		return ErrInvalidKeyId
+	}
+	if len(key) != ed25519.PublicKeySize {
+		return ErrInvalidKeySize
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.ed25519Keys[keyId] = key
+	return nil
+}
+
+func (v *KeyVerifier) RemoveKey(keyId string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	delete(v.hmacKeys, keyId)
+	delete(v.ed25519Keys, keyId)
+}
+
+/*
+	Checks a signature. The algorithm must match the kind of key stored under
+	the key id so an Ed25519 public key can never be used as an HMAC secret.
+*/
+func (v *KeyVerifier) Verify(algorithm string, keyId string, data []byte, signature []byte) error {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	switch algorithm {
+	case AlgorithmHMACSHA256:
+		key, ok := v.hmacKeys[keyId]
+		if !ok {
+			return ErrUnknownKey
+		}
+		if !hmac.Equal(signHMAC(key, data), signature) {
+			return ErrInvalidSignature
+		}
+	case AlgorithmEd25519:
+		key, ok := v.ed25519Keys[keyId]
+		if !ok {
+			return ErrUnknownKey
+		}
+		if !ed25519.Verify(key, data, signature) {
+			return ErrInvalidSignature
+		}
+	default:
+		return ErrUnknownAlgorithm
+	}
+
+	return nil
+}
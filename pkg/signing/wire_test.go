@@ -0,0 +1,180 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package signing
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+/*
+	Sends a table through the amqp091 encoder and decoder without a broker. The
+	client encodes its connection properties into connection.start-ok, a second
+	handshake hands those exact bytes back as the server properties of
+	connection.start and the client decodes them into Connection.Properties.
+*/
+func wireRoundTrip(t *testing.T, table amqp.Table) amqp.Table {
+	t.Helper()
+
+	encoded := captureClientProperties(t, table)
+	decoded := serveProperties(t, encoded)
+
+	// added by amqp091 to every handshake
+	delete(decoded, "capabilities")
+	return decoded
+}
+
+func captureClientProperties(t *testing.T, table amqp.Table) []byte {
+	t.Helper()
+
+	// the client adds its capabilities to the table it is given
+	properties := make(amqp.Table, len(table))
+	for k, v := range table {
+		properties[k] = v
+	}
+
+	server, client := net.Pipe()
+	defer server.Close()
+
+	go amqp.Open(client, testConfig(properties))
+
+	readProtocolHeader(t, server)
+	writeMethod(t, server, 10, 10, connectionStart(emptyTable()))
+
+	payload := readMethod(t, server, 10, 11)
+	size := binary.BigEndian.Uint32(payload[0:4])
+	return append([]byte(nil), payload[0:4+size]...)
+}
+
+func serveProperties(t *testing.T, encoded []byte) amqp.Table {
+	t.Helper()
+
+	server, client := net.Pipe()
+	defer server.Close()
+
+	type result struct {
+		conn *amqp.Connection
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		conn, err := amqp.Open(client, testConfig(nil))
+		done <- result{conn, err}
+	}()
+
+	readProtocolHeader(t, server)
+	writeMethod(t, server, 10, 10, connectionStart(encoded))
+	readMethod(t, server, 10, 11)
+
+	var tune bytes.Buffer
+	binary.Write(&tune, binary.BigEndian, uint16(0))
+	binary.Write(&tune, binary.BigEndian, uint32(131072))
+	binary.Write(&tune, binary.BigEndian, uint16(0))
+	writeMethod(t, server, 10, 30, tune.Bytes())
+	readMethod(t, server, 10, 31)
+	readMethod(t, server, 10, 40)
+	writeMethod(t, server, 10, 41, []byte{0})
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			t.Fatalf("amqp.Open failed. Err: %v", r.err)
+		}
+		return r.conn.Properties
+	case <-time.After(5 * time.Second):
+		t.Fatalf("handshake timed out")
+	}
+	return nil
+}
+
+func testConfig(properties amqp.Table) amqp.Config {
+	return amqp.Config{
+		SASL:       []amqp.Authentication{&amqp.PlainAuth{Username: "guest", Password: "guest"}},
+		Vhost:      "/",
+		Properties: properties,
+		Locale:     "en_US",
+	}
+}
+
+func emptyTable() []byte {
+	return []byte{0, 0, 0, 0}
+}
+
+func connectionStart(serverProperties []byte) []byte {
+	var payload bytes.Buffer
+	payload.Write([]byte{0, 9})
+	payload.Write(serverProperties)
+	writeLongstr(&payload, "PLAIN")
+	writeLongstr(&payload, "en_US")
+	return payload.Bytes()
+}
+
+func writeLongstr(buf *bytes.Buffer, s string) {
+	binary.Write(buf, binary.BigEndian, uint32(len(s)))
+	buf.WriteString(s)
+}
+
+func readProtocolHeader(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	header := make([]byte, 8)
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		t.Fatalf("reading protocol header failed. Err: %v", err)
+	}
+}
+
+func writeMethod(t *testing.T, conn net.Conn, class, method uint16, arguments []byte) {
+	t.Helper()
+
+	var payload bytes.Buffer
+	binary.Write(&payload, binary.BigEndian, class)
+	binary.Write(&payload, binary.BigEndian, method)
+	payload.Write(arguments)
+
+	var frame bytes.Buffer
+	frame.WriteByte(1)
+	binary.Write(&frame, binary.BigEndian, uint16(0))
+	binary.Write(&frame, binary.BigEndian, uint32(payload.Len()))
+	frame.Write(payload.Bytes())
+	frame.WriteByte(0xce)
+
+	_, err := conn.Write(frame.Bytes())
+	if err != nil {
+		t.Fatalf("writing method %d.%d failed. Err: %v", class, method, err)
+	}
+}
+
+/*
+	Reads a method frame and returns its arguments
+*/
+func readMethod(t *testing.T, conn net.Conn, class, method uint16) []byte {
+	t.Helper()
+
+	header := make([]byte, 7)
+	_, err := io.ReadFull(conn, header)
+	if err != nil {
+		t.Fatalf("reading frame header failed. Err: %v", err)
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(header[3:7])+1)
+	_, err = io.ReadFull(conn, payload)
+	if err != nil {
+		t.Fatalf("reading frame payload failed. Err: %v", err)
+	}
+
+	gotClass := binary.BigEndian.Uint16(payload[0:2])
+	gotMethod := binary.BigEndian.Uint16(payload[2:4])
+	if gotClass != class || gotMethod != method {
+		t.Fatalf("got method %d.%d, want %d.%d", gotClass, gotMethod, class, method)
+	}
+
+	return payload[4 : len(payload)-1]
+}
@@ -0,0 +1,48 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package spool
+
+import (
+	"errors"
+)
+
+const (
+	// DefaultDirectory where spools are kept when none is given
+	DefaultDirectory string = ".rabbitmq-manager/spool"
+
+	// DefaultSegmentSize size at which the spool starts a new segment file
+	DefaultSegmentSize int64 = 16 * 1024 * 1024
+
+	// DefaultMaxBytes total size the spool may grow to
+	DefaultMaxBytes int64 = 1024 * 1024 * 1024
+
+	segmentExtension string = ".seg"
+	cursorFilename   string = "cursor"
+
+	// record header is the payload length followed by its crc32
+	recordHeaderSize int64 = 8
+)
+
+var (
+	// ErrSpoolFull the spool reached its size limit
+	ErrSpoolFull = errors.New("the spool reached its size limit")
+
+	// ErrSpoolEmpty the spool has no records left to replay
+	ErrSpoolEmpty = errors.New("the spool has no records left to replay")
+
+	// ErrSpoolClosed the spool has been closed
+	ErrSpoolClosed = errors.New("the spool has been closed")
+
+	// ErrInvalidSpoolSize the segment size and size limit must be positive
+	ErrInvalidSpoolSize = errors.New("the segment size and size limit must be positive")
+
+	// ErrSegmentTooLarge the segment size cannot exceed the size limit
+	ErrSegmentTooLarge = errors.New("the segment size cannot exceed the size limit")
+
+	// ErrCorruptRecord a spooled record failed its checksum
+	ErrCorruptRecord = errors.New("a spooled record failed its checksum")
+
+	// ErrCorruptCursor the stored replay position could not be parsed
+	ErrCorruptCursor = errors.New("the stored replay position could not be parsed")
+)
@@ -0,0 +1,482 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package spool
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+)
+
+func init() {
+	// header values travel as interfaces
+	gob.Register(amqp.Table{})
+	gob.Register([]interface{}{})
+	gob.Register(time.Time{})
+	gob.Register(amqp.Decimal{})
+}
+
+func Open(directory string, segmentSize, maxBytes int64) (*Spool, error) {
+	if directory == "" {
+		directory = DefaultDirectory
+	}
+	if maxBytes == 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	if segmentSize == 0 {
+		segmentSize = DefaultSegmentSize
+		if segmentSize > maxBytes {
+			segmentSize = maxBytes
+		}
+	}
+	if segmentSize < 0 || maxBytes < 0 {
+		return nil, ErrInvalidSpoolSize
+	}
+	if segmentSize > maxBytes {
+		return nil, ErrSegmentTooLarge
+	}
+
+	err := os.MkdirAll(directory, 0755)
+	if err != nil {
+		klog.V(1).Infof("MkdirAll %s failed. Err: %v\n", directory, err)
+		return nil, err
+	}
+
+	s := &Spool{
+		directory:   directory,
+		segmentSize: segmentSize,
+		maxBytes:    maxBytes,
+	}
+
+	err = s.load()
+	if err != nil {
+		klog.V(1).Infof("load %s failed. Err: %v\n", directory, err)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *Spool) segmentFilename(id uint64) string {
+	return filepath.Join(s.directory, fmt.Sprintf("%020d%s", id, segmentExtension))
+}
+
+/*
+	Picks up the segments and replay position left by an earlier process
+*/
+func (s *Spool) load() error {
+	entries, err := os.ReadDir(s.directory)
+	if err != nil {
+		return err
+	}
+
+	s.segments = make([]segment, 0)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExtension) {
+			continue
+		}
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExtension), 10, 64)
+		if err != nil {
+			klog.V(1).Infof("Ignoring unknown spool file %s\n", name)
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		s.segments = append(s.segments, segment{id: id, size: info.Size()})
+	}
+	sort.Slice(s.segments, func(i, j int) bool {
+		return s.segments[i].id < s.segments[j].id
+	})
+
+	err = s.loadCursor()
+	if err != nil {
+		return err
+	}
+
+	// segments before the cursor were replayed but not yet removed
+	for len(s.segments) > 0 && s.segments[0].id < s.readSegment {
+		err = os.Remove(s.segmentFilename(s.segments[0].id))
+		if err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		s.segments = s.segments[1:]
+	}
+	if len(s.segments) > 0 && s.segments[0].id != s.readSegment {
+		s.readSegment = s.segments[0].id
+		s.readOffset = 0
+	}
+
+	if len(s.segments) == 0 {
+		return nil
+	}
+
+	// a crash while appending can leave a partial record at the very end
+	last := &s.segments[len(s.segments)-1]
+	valid, err := s.validLength(last.id)
+	if err != nil {
+		return err
+	}
+	if valid < last.size {
+		klog.V(1).Infof("Truncating partial record in spool segment %d\n", last.id)
+		err = os.Truncate(s.segmentFilename(last.id), valid)
+		if err != nil {
+			return err
+		}
+		last.size = valid
+	}
+
+	for _, seg := range s.segments {
+		s.size += seg.size
+	}
+
+	s.active, err = os.OpenFile(s.segmentFilename(last.id), os.O_WRONLY|os.O_APPEND, 0644)
+	return err
+}
+
+func (s *Spool) loadCursor() error {
+	data, err := os.ReadFile(filepath.Join(s.directory, cursorFilename))
+	if os.IsNotExist(err) {
+		if len(s.segments) > 0 {
+			s.readSegment = s.segments[0].id
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fields := strings.Fields(string(data))
+	if len(fields) != 2 {
+		return ErrCorruptCursor
+	}
+	s.readSegment, err = strconv.ParseUint(fields[0], 10, 64)
+	if err != nil {
+		return ErrCorruptCursor
+	}
+	s.readOffset, err = strconv.ParseInt(fields[1], 10, 64)
+	if err != nil {
+		return ErrCorruptCursor
+	}
+
+	return nil
+}
+
+func (s *Spool) storeCursor() error {
+	data := []byte(fmt.Sprintf("%d %d", s.readSegment, s.readOffset))
+	err := common.WriteFileAtomic(filepath.Join(s.directory, cursorFilename), data, 0644)
+	if err != nil {
+		klog.V(1).Infof("WriteFileAtomic cursor failed. Err: %v\n", err)
+		return err
+	}
+
+	return nil
+}
+
+/*
+	Length of the segment up to the end of its last complete record
+*/
+func (s *Spool) validLength(id uint64) (int64, error) {
+	file, err := os.Open(s.segmentFilename(id))
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	var offset int64
+	for {
+		size, err := recordSize(file, offset)
+		if err != nil {
+			return offset, nil
+		}
+		offset += size
+	}
+}
+
+func recordSize(file *os.File, offset int64) (int64, error) {
+	header := make([]byte, recordHeaderSize)
+	_, err := file.ReadAt(header, offset)
+	if err != nil {
+		return 0, err
+	}
+
+	length := int64(binary.BigEndian.Uint32(header[0:4]))
+	payload := make([]byte, length)
+	_, err = file.ReadAt(payload, offset+recordHeaderSize)
+	if err != nil {
+		return 0, err
+	}
+	if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:8]) {
+		return 0, ErrCorruptRecord
+	}
+
+	return recordHeaderSize + length, nil
+}
+
+/*
+	Appends a record and syncs it to disk, so it survives a process restart
+*/
+func (s *Spool) Append(record *Record) error {
+	var payload bytes.Buffer
+	err := gob.NewEncoder(&payload).Encode(record)
+	if err != nil {
+		klog.V(1).Infof("Encode failed. Err: %v\n", err)
+		return err
+	}
+
+	data := make([]byte, recordHeaderSize, recordHeaderSize+int64(payload.Len()))
+	binary.BigEndian.PutUint32(data[0:4], uint32(payload.Len()))
+	binary.BigEndian.PutUint32(data[4:8], crc32.ChecksumIEEE(payload.Bytes()))
+	data = append(data, payload.Bytes()...)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSpoolClosed
+	}
+	if s.size+int64(len(data)) > s.maxBytes {
+		return ErrSpoolFull
+	}
+
+	if s.active == nil || s.segments[len(s.segments)-1].size+int64(len(data)) > s.segmentSize {
+		err = s.rotate()
+		if err != nil {
+			klog.V(1).Infof("rotate failed. Err: %v\n", err)
+			return err
+		}
+	}
+
+	_, err = s.active.Write(data)
+	if err != nil {
+		klog.V(1).Infof("Write failed. Err: %v\n", err)
+		return err
+	}
+	err = s.active.Sync()
+	if err != nil {
+		klog.V(1).Infof("Sync failed. Err: %v\n", err)
+		return err
+	}
+
+	s.segments[len(s.segments)-1].size += int64(len(data))
+	s.size += int64(len(data))
+
+	return nil
+}
+
+/*
+	Starts a new segment, unless the active one is still empty
+*/
+func (s *Spool) rotate() error {
+	var id uint64 = 1
+	if len(s.segments) > 0 {
+		last := s.segments[len(s.segments)-1]
+		if s.active != nil && last.size == 0 {
+			return nil
+		}
+		id = last.id + 1
+	}
+
+	if s.active != nil {
+		s.active.Close()
+		s.active = nil
+	}
+
+	file, err := os.OpenFile(s.segmentFilename(id), os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+	s.active = file
+
+	if len(s.segments) == 0 {
+		s.readSegment = id
+		s.readOffset = 0
+	}
+	s.segments = append(s.segments, segment{id: id})
+
+	return nil
+}
+
+/*
+	Returns the next record to replay without consuming it. Call Commit once the
+	record has been published.
+*/
+func (s *Spool) Peek() (*Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil, ErrSpoolClosed
+	}
+
+	for {
+		if len(s.segments) == 0 {
+			return nil, ErrSpoolEmpty
+		}
+
+		seg := s.segments[0]
+		if s.readOffset >= seg.size {
+			if len(s.segments) == 1 && seg.size == 0 {
+				return nil, ErrSpoolEmpty
+			}
+			err := s.releaseHead()
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		record, size, err := s.read(seg.id, s.readOffset)
+		if err == ErrCorruptRecord || err == io.ErrUnexpectedEOF || err == io.EOF {
+			// nothing after a damaged record can be framed, give up on the segment
+			klog.V(1).Infof("Skipping damaged spool segment %d at %d. Err: %v\n", seg.id, s.readOffset, err)
+			s.readOffset = seg.size
+			continue
+		}
+		if err != nil {
+			klog.V(1).Infof("read failed. Err: %v\n", err)
+			return nil, err
+		}
+
+		s.peekSize = size
+		return record, nil
+	}
+}
+
+func (s *Spool) read(id uint64, offset int64) (*Record, int64, error) {
+	file, err := os.Open(s.segmentFilename(id))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer file.Close()
+
+	size, err := recordSize(file, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	payload := make([]byte, size-recordHeaderSize)
+	_, err = file.ReadAt(payload, offset+recordHeaderSize)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var record Record
+	err = gob.NewDecoder(bytes.NewReader(payload)).Decode(&record)
+	if err != nil {
+		return nil, 0, ErrCorruptRecord
+	}
+
+	return &record, size, nil
+}
+
+/*
+	Consumes the record returned by the last Peek
+*/
+func (s *Spool) Commit() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return ErrSpoolClosed
+	}
+	if s.peekSize == 0 {
+		return nil
+	}
+
+	s.readOffset += s.peekSize
+	s.peekSize = 0
+
+	if s.readOffset >= s.segments[0].size {
+		return s.releaseHead()
+	}
+	return s.storeCursor()
+}
+
+/*
+	Gives back the space of the fully replayed first segment. When it is also the
+	segment being appended to a fresh one is started first, otherwise a drained
+	spool would keep counting replayed records against its size limit.
+*/
+func (s *Spool) releaseHead() error {
+	if len(s.segments) == 1 {
+		err := s.rotate()
+		if err != nil {
+			klog.V(1).Infof("rotate failed. Err: %v\n", err)
+			return err
+		}
+	}
+	return s.removeHead()
+}
+
+/*
+	Removes the fully replayed first segment and moves the cursor to the next one
+*/
+func (s *Spool) removeHead() error {
+	head := s.segments[0]
+	s.segments = s.segments[1:]
+	s.size -= head.size
+
+	s.readSegment = s.segments[0].id
+	s.readOffset = 0
+
+	err := s.storeCursor()
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(s.segmentFilename(head.id))
+	if err != nil && !os.IsNotExist(err) {
+		klog.V(1).Infof("Remove spool segment %d failed. Err: %v\n", head.id, err)
+		return err
+	}
+
+	return nil
+}
+
+/*
+	Reports whether every record has been replayed
+*/
+func (s *Spool) Empty() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(s.segments) == 0 {
+		return true
+	}
+	return len(s.segments) == 1 && s.readOffset >= s.segments[0].size
+}
+
+func (s *Spool) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+
+	if s.active != nil {
+		err := s.active.Close()
+		s.active = nil
+		return err
+	}
+	return nil
+}
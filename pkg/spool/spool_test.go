@@ -0,0 +1,336 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package spool
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+func newRecord(i int, size int) *Record {
+	return &Record{
+		RoutingKey: fmt.Sprintf("key.%d", i),
+		Publishing: amqp.Publishing{
+			MessageId: fmt.Sprintf("id-%d", i),
+			Headers:   amqp.Table{"n": int32(i)},
+			Body:      make([]byte, size),
+		},
+	}
+}
+
+func drain(t *testing.T, s *Spool) []string {
+	t.Helper()
+
+	var ids []string
+	for {
+		record, err := s.Peek()
+		if err == ErrSpoolEmpty {
+			return ids
+		}
+		if err != nil {
+			t.Fatalf("Peek failed. Err: %v", err)
+		}
+		ids = append(ids, record.Publishing.MessageId)
+
+		err = s.Commit()
+		if err != nil {
+			t.Fatalf("Commit failed. Err: %v", err)
+		}
+	}
+}
+
+func TestOpenValidation(t *testing.T) {
+	tests := []struct {
+		name        string
+		segmentSize int64
+		maxBytes    int64
+		err         error
+	}{
+		{"defaults", 0, 0, nil},
+		{"default segment clamped to small limit", 0, 64 * 1024, nil},
+		{"negative segment", -1, 0, ErrInvalidSpoolSize},
+		{"negative limit", 0, -1, ErrInvalidSpoolSize},
+		{"segment larger than limit", 2048, 1024, ErrSegmentTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Open(t.TempDir(), tt.segmentSize, tt.maxBytes)
+			if err != tt.err {
+				t.Fatalf("Open() err = %v, want %v", err, tt.err)
+			}
+			if s != nil {
+				s.Close()
+			}
+		})
+	}
+}
+
+func TestAppendReplayInOrder(t *testing.T) {
+	tests := []struct {
+		name        string
+		segmentSize int64
+		count       int
+	}{
+		{"single segment", 0, 10},
+		{"many segments", 512, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Open(t.TempDir(), tt.segmentSize, 0)
+			if err != nil {
+				t.Fatalf("Open failed. Err: %v", err)
+			}
+			defer s.Close()
+
+			for i := 0; i < tt.count; i++ {
+				err = s.Append(newRecord(i, 100))
+				if err != nil {
+					t.Fatalf("Append %d failed. Err: %v", i, err)
+				}
+			}
+
+			ids := drain(t, s)
+			if len(ids) != tt.count {
+				t.Fatalf("replayed %d records, want %d", len(ids), tt.count)
+			}
+			for i, id := range ids {
+				if id != fmt.Sprintf("id-%d", i) {
+					t.Fatalf("record %d is %s", i, id)
+				}
+			}
+			if !s.Empty() {
+				t.Fatalf("spool not empty after replay")
+			}
+		})
+	}
+}
+
+func TestDrainedSpoolReleasesSpace(t *testing.T) {
+	tests := []struct {
+		name        string
+		segmentSize int64
+		maxBytes    int64
+	}{
+		{"default segment", 0, 64 * 1024},
+		{"segment equals limit", 64 * 1024, 64 * 1024},
+		{"small segments", 4 * 1024, 64 * 1024},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := Open(t.TempDir(), tt.segmentSize, tt.maxBytes)
+			if err != nil {
+				t.Fatalf("Open failed. Err: %v", err)
+			}
+			defer s.Close()
+
+			// far more than the limit in total, but never more than one record at a time
+			for i := 0; i < 500; i++ {
+				err = s.Append(newRecord(i, 1024))
+				if err != nil {
+					t.Fatalf("round %d: Append failed. Err: %v (size %d)", i, err, s.size)
+				}
+				if ids := drain(t, s); len(ids) != 1 {
+					t.Fatalf("round %d: replayed %d records", i, len(ids))
+				}
+			}
+		})
+	}
+}
+
+func TestAppendFull(t *testing.T) {
+	s, err := Open(t.TempDir(), 0, 4*1024)
+	if err != nil {
+		t.Fatalf("Open failed. Err: %v", err)
+	}
+	defer s.Close()
+
+	var appended int
+	for {
+		err = s.Append(newRecord(appended, 512))
+		if err == ErrSpoolFull {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Append failed. Err: %v", err)
+		}
+		appended++
+	}
+	if appended == 0 {
+		t.Fatalf("nothing fit into the spool")
+	}
+
+	// replaying makes room again
+	drain(t, s)
+	err = s.Append(newRecord(appended, 512))
+	if err != nil {
+		t.Fatalf("Append after drain failed. Err: %v", err)
+	}
+}
+
+func TestReopenResumesAtCursor(t *testing.T) {
+	dir := t.TempDir()
+
+	s, err := Open(dir, 512, 0)
+	if err != nil {
+		t.Fatalf("Open failed. Err: %v", err)
+	}
+	for i := 0; i < 20; i++ {
+		err = s.Append(newRecord(i, 100))
+		if err != nil {
+			t.Fatalf("Append failed. Err: %v", err)
+		}
+	}
+	for i := 0; i < 7; i++ {
+		_, err = s.Peek()
+		if err != nil {
+			t.Fatalf("Peek failed. Err: %v", err)
+		}
+		err = s.Commit()
+		if err != nil {
+			t.Fatalf("Commit failed. Err: %v", err)
+		}
+	}
+	// peeked but never committed, so it is replayed again
+	_, err = s.Peek()
+	if err != nil {
+		t.Fatalf("Peek failed. Err: %v", err)
+	}
+	s.Close()
+
+	s, err = Open(dir, 512, 0)
+	if err != nil {
+		t.Fatalf("reopen failed. Err: %v", err)
+	}
+	defer s.Close()
+
+	ids := drain(t, s)
+	if len(ids) != 13 || ids[0] != "id-7" || ids[12] != "id-19" {
+		t.Fatalf("replayed %v after reopen", ids)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	tests := []struct {
+		name    string
+		damage  func(t *testing.T, filename string)
+		replays int
+	}{
+		{
+			name: "torn tail is truncated",
+			damage: func(t *testing.T, filename string) {
+				info, _ := os.Stat(filename)
+				if err := os.Truncate(filename, info.Size()-10); err != nil {
+					t.Fatal(err)
+				}
+			},
+			replays: 4,
+		},
+		{
+			name: "garbage appended after the last record",
+			damage: func(t *testing.T, filename string) {
+				file, err := os.OpenFile(filename, os.O_WRONLY|os.O_APPEND, 0644)
+				if err != nil {
+					t.Fatal(err)
+				}
+				file.Write([]byte{0, 0, 0, 3, 1, 2, 3})
+				file.Close()
+			},
+			replays: 5,
+		},
+		{
+			name: "checksum mismatch ends the segment",
+			damage: func(t *testing.T, filename string) {
+				data, err := os.ReadFile(filename)
+				if err != nil {
+					t.Fatal(err)
+				}
+				// flip a payload byte of the first record
+				data[recordHeaderSize+4] ^= 0xff
+				if err := os.WriteFile(filename, data, 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			replays: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			s, err := Open(dir, 0, 0)
+			if err != nil {
+				t.Fatalf("Open failed. Err: %v", err)
+			}
+			for i := 0; i < 5; i++ {
+				err = s.Append(newRecord(i, 100))
+				if err != nil {
+					t.Fatalf("Append failed. Err: %v", err)
+				}
+			}
+			s.Close()
+
+			matches, _ := filepath.Glob(filepath.Join(dir, "*"+segmentExtension))
+			if len(matches) != 1 {
+				t.Fatalf("found %d segments", len(matches))
+			}
+			tt.damage(t, matches[0])
+
+			s, err = Open(dir, 0, 0)
+			if err != nil {
+				t.Fatalf("reopen failed. Err: %v", err)
+			}
+			defer s.Close()
+
+			ids := drain(t, s)
+			if len(ids) != tt.replays {
+				t.Fatalf("replayed %d records, want %d", len(ids), tt.replays)
+			}
+
+			// the spool stays usable after recovery
+			err = s.Append(newRecord(99, 100))
+			if err != nil {
+				t.Fatalf("Append after recovery failed. Err: %v", err)
+			}
+			if ids := drain(t, s); len(ids) != 1 || ids[0] != "id-99" {
+				t.Fatalf("replayed %v after recovery", ids)
+			}
+		})
+	}
+}
+
+func TestCorruptCursor(t *testing.T) {
+	dir := t.TempDir()
+	err := os.WriteFile(filepath.Join(dir, cursorFilename), []byte("garbage"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Open(dir, 0, 0)
+	if err != ErrCorruptCursor {
+		t.Fatalf("Open() err = %v, want %v", err, ErrCorruptCursor)
+	}
+}
+
+func TestClosed(t *testing.T) {
+	s, err := Open(t.TempDir(), 0, 0)
+	if err != nil {
+		t.Fatalf("Open failed. Err: %v", err)
+	}
+	s.Close()
+
+	if err := s.Append(newRecord(0, 1)); err != ErrSpoolClosed {
+		t.Fatalf("Append() err = %v, want %v", err, ErrSpoolClosed)
+	}
+	if _, err := s.Peek(); err != ErrSpoolClosed {
+		t.Fatalf("Peek() err = %v, want %v", err, ErrSpoolClosed)
+	}
+}
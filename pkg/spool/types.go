@@ -0,0 +1,46 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package spool
+
+import (
+	"os"
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+/*
+	A message waiting in the spool, already prepared for publishing
+*/
+type Record struct {
+	RoutingKey string
+	Publishing amqp.Publishing
+}
+
+type segment struct {
+	id   uint64
+	size int64
+}
+
+/*
+	Append-only log of records split over segment files. Records are replayed in
+	the order they were appended and a segment is removed once fully replayed.
+*/
+type Spool struct {
+	directory   string
+	segmentSize int64
+	maxBytes    int64
+
+	segments []segment
+	active   *os.File
+	size     int64
+	closed   bool
+
+	// replay position
+	readSegment uint64
+	readOffset  int64
+	peekSize    int64
+
+	mu sync.Mutex
+}
@@ -0,0 +1,137 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Maps a handler result onto a concrete outcome. Without an explicit outcome nil
+	acks and an error applies ErrorOutcome. When ErrorOutcome is left at the default
+	a failed message is requeued once and dead-lettered if it fails again.
+*/
+func (s *Subscriber) resolveOutcome(d *amqp.Delivery, outcome interfaces.AckOutcome, err error) interfaces.AckOutcome {
+	if outcome != interfaces.AckOutcomeDefault {
+		return outcome
+	}
+	if err == nil {
+		return interfaces.AckOutcomeAck
+	}
+	if s.options.ErrorOutcome != interfaces.AckOutcomeDefault {
+		return s.options.ErrorOutcome
+	}
+	if d.Redelivered {
+		return interfaces.AckOutcomeNackDiscard
+	}
+	return interfaces.AckOutcomeNackRequeue
+}
+
+/*
+	Issues the AMQP call for an outcome and returns the outcome the broker
+	actually got. Without acknowledgements the broker settled the message on
+	delivery, and a stream is always acked as acks only grant it credit.
+*/
+func (s *Subscriber) acknowledge(d *amqp.Delivery, outcome interfaces.AckOutcome) (interfaces.AckOutcome, error) {
+	if s.options.NoAck {
+		return interfaces.AckOutcomeAck, nil
+	}
+
+	// streams only use acks to grant credit, the message stays in the stream regardless
+	if s.stream {
+		return interfaces.AckOutcomeAck, d.Ack(false)
+	}
+
+	switch outcome {
+	case interfaces.AckOutcomeNackRequeue:
+		klog.V(4).Infof("Nack (requeue) %d on %s\n", d.DeliveryTag, s.GetName())
+		return outcome, d.Nack(false, true)
+	case interfaces.AckOutcomeNackDiscard:
+		klog.V(4).Infof("Nack (discard) %d on %s\n", d.DeliveryTag, s.GetName())
+		return outcome, d.Nack(false, false)
+	case interfaces.AckOutcomeReject:
+		klog.V(4).Infof("Reject %d on %s\n", d.DeliveryTag, s.GetName())
+		return outcome, d.Reject(false)
+	default:
+		return interfaces.AckOutcomeAck, d.Ack(false)
+	}
+}
+
+/*
+	Settles a delivery: acknowledges it, counts what the broker got, releases a
+	claim checked body once acked and for streams records the offset
+*/
+func (s *Subscriber) complete(d *amqp.Delivery, outcome interfaces.AckOutcome) error {
+	sent, err := s.acknowledge(d, outcome)
+	if err != nil {
+		return err
+	}
+
+	s.stats.countOutcome(sent)
+
+	if outcome == interfaces.AckOutcomeAck {
+		s.releaseClaim(d)
+	}
+
+	if s.stream {
+		s.settleOffset(d, outcome)
+	}
+
+	return nil
+}
+
+/*
+	Runs the handler for a single delivery and acknowledges it. In DeferredAck mode
+	the handler owns the acknowledgement through the token unless it fails or
+	returns an explicit outcome.
+*/
+func (s *Subscriber) processDelivery(d *amqp.Delivery, delivery *interfaces.Delivery) {
+	klog.V(5).Infof(" [x] %s\n", d.Body)
+
+	if s.breaker != nil && !s.breaker.Allow() {
+		s.holdBack(d)
+		return
+	}
+
+	err := s.unwrap(delivery)
+	if err != nil {
+		klog.V(1).Infof("unwrap %d failed. Err: %v\n", d.DeliveryTag, err)
+		s.discardUnreadable(d, delivery, err)
+		return
+	}
+
+	var token *acknowledger
+	if s.options.DeferredAck {
+		token = s.newAcknowledger(d)
+		delivery.Acknowledger = token
+	}
+
+	outcome, err := s.handler.HandleDelivery(delivery)
+	if err != nil {
+		klog.V(1).Infof("HandleDelivery() failed. Err: %v\n", err)
+	}
+	if s.breaker != nil {
+		s.breaker.Record(err)
+	}
+
+	if token != nil {
+		if err == nil && outcome == interfaces.AckOutcomeDefault {
+			return
+		}
+
+		err = token.resolve(s.resolveOutcome(d, outcome, err))
+		if err != nil && err != ErrAlreadyAcknowledged {
+			klog.V(1).Infof("resolve() failed. Err: %v\n", err)
+		}
+		return
+	}
+
+	err = s.complete(d, s.resolveOutcome(d, outcome, err))
+	if err != nil {
+		klog.V(1).Infof("complete() failed. Err: %v\n", err)
+	}
+}
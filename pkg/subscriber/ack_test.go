@@ -0,0 +1,101 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"errors"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+func TestResolveOutcome(t *testing.T) {
+	errHandler := errors.New("handler failed")
+
+	tests := []struct {
+		name         string
+		outcome      interfaces.AckOutcome
+		err          error
+		redelivered  bool
+		errorOutcome interfaces.AckOutcome
+		want         interfaces.AckOutcome
+	}{
+		{"nil acks", interfaces.AckOutcomeDefault, nil, false, interfaces.AckOutcomeDefault, interfaces.AckOutcomeAck},
+		{"nil acks a redelivery", interfaces.AckOutcomeDefault, nil, true, interfaces.AckOutcomeDefault, interfaces.AckOutcomeAck},
+		{"error requeues once", interfaces.AckOutcomeDefault, errHandler, false, interfaces.AckOutcomeDefault, interfaces.AckOutcomeNackRequeue},
+		{"error on a redelivery discards", interfaces.AckOutcomeDefault, errHandler, true, interfaces.AckOutcomeDefault, interfaces.AckOutcomeNackDiscard},
+		{"ErrorOutcome overrides", interfaces.AckOutcomeDefault, errHandler, false, interfaces.AckOutcomeReject, interfaces.AckOutcomeReject},
+		{"ErrorOutcome on a redelivery", interfaces.AckOutcomeDefault, errHandler, true, interfaces.AckOutcomeNackRequeue, interfaces.AckOutcomeNackRequeue},
+		{"ErrorOutcome ignored without error", interfaces.AckOutcomeDefault, nil, false, interfaces.AckOutcomeReject, interfaces.AckOutcomeAck},
+		{"explicit outcome wins over error", interfaces.AckOutcomeAck, errHandler, true, interfaces.AckOutcomeReject, interfaces.AckOutcomeAck},
+		{"explicit outcome without error", interfaces.AckOutcomeNackDiscard, nil, false, interfaces.AckOutcomeDefault, interfaces.AckOutcomeNackDiscard},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Subscriber{
+				options: SubscriberOptions{
+					SubscriberOptions: &interfaces.SubscriberOptions{ErrorOutcome: tt.errorOutcome},
+				},
+			}
+
+			got := s.resolveOutcome(&amqp.Delivery{Redelivered: tt.redelivered}, tt.outcome, tt.err)
+			if got != tt.want {
+				t.Fatalf("resolveOutcome() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAcknowledge(t *testing.T) {
+	tests := []struct {
+		name    string
+		noAck   bool
+		stream  bool
+		outcome interfaces.AckOutcome
+		sent    interfaces.AckOutcome
+		call    *ackCall
+	}{
+		{"ack", false, false, interfaces.AckOutcomeAck, interfaces.AckOutcomeAck, &ackCall{"ack", 1, false, false}},
+		{"requeue", false, false, interfaces.AckOutcomeNackRequeue, interfaces.AckOutcomeNackRequeue, &ackCall{"nack", 1, false, true}},
+		{"discard", false, false, interfaces.AckOutcomeNackDiscard, interfaces.AckOutcomeNackDiscard, &ackCall{"nack", 1, false, false}},
+		{"reject", false, false, interfaces.AckOutcomeReject, interfaces.AckOutcomeReject, &ackCall{"reject", 1, false, false}},
+		{"default acks", false, false, interfaces.AckOutcomeDefault, interfaces.AckOutcomeAck, &ackCall{"ack", 1, false, false}},
+		{"no ack sends nothing", true, false, interfaces.AckOutcomeNackRequeue, interfaces.AckOutcomeAck, nil},
+		{"stream always acks", false, true, interfaces.AckOutcomeReject, interfaces.AckOutcomeAck, &ackCall{"ack", 1, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Subscriber{
+				options: SubscriberOptions{
+					SubscriberOptions: &interfaces.SubscriberOptions{Name: "test", NoAck: tt.noAck},
+				},
+				stream: tt.stream,
+			}
+
+			ack := &fakeAcknowledger{}
+			sent, err := s.acknowledge(&amqp.Delivery{Acknowledger: ack, DeliveryTag: 1}, tt.outcome)
+			if err != nil {
+				t.Fatalf("acknowledge failed. Err: %v", err)
+			}
+			if sent != tt.sent {
+				t.Fatalf("acknowledge() = %d, want %d", sent, tt.sent)
+			}
+
+			calls := ack.get()
+			if tt.call == nil {
+				if len(calls) != 0 {
+					t.Fatalf("unexpected settlements %v", calls)
+				}
+				return
+			}
+			if len(calls) != 1 || calls[0] != *tt.call {
+				t.Fatalf("settlements = %v, want %v", calls, *tt.call)
+			}
+		})
+	}
+}
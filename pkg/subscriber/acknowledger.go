@@ -0,0 +1,119 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"sync"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Deferred acknowledgement token handed to the handler in DeferredAck mode
+*/
+type acknowledger struct {
+	subscriber *Subscriber
+	delivery   amqp.Delivery
+	resolved   bool
+	expired    bool
+	mu         sync.Mutex
+}
+
+func (s *Subscriber) newAcknowledger(d *amqp.Delivery) *acknowledger {
+	token := &acknowledger{
+		subscriber: s,
+		delivery:   *d,
+	}
+
+	s.ackMu.Lock()
+	s.outstanding[token] = struct{}{}
+	s.ackMu.Unlock()
+
+	return token
+}
+
+func (a *acknowledger) Ack() error {
+	return a.resolve(interfaces.AckOutcomeAck)
+}
+
+func (a *acknowledger) Nack(requeue bool) error {
+	if requeue {
+		return a.resolve(interfaces.AckOutcomeNackRequeue)
+	}
+	return a.resolve(interfaces.AckOutcomeNackDiscard)
+}
+
+func (a *acknowledger) Reject() error {
+	return a.resolve(interfaces.AckOutcomeReject)
+}
+
+func (a *acknowledger) resolve(outcome interfaces.AckOutcome) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.expired {
+		return ErrAcknowledgerExpired
+	}
+	if a.resolved {
+		return ErrAlreadyAcknowledged
+	}
+	a.resolved = true
+
+	a.subscriber.ackMu.Lock()
+	delete(a.subscriber.outstanding, a)
+	a.subscriber.ackMu.Unlock()
+
+	return a.subscriber.complete(&a.delivery, outcome)
+}
+
+/*
+	Requeues every token the handler never resolved. Called on shutdown while the
+	channel is still open.
+*/
+func (s *Subscriber) nackOutstanding() {
+	s.ackMu.Lock()
+	tokens := make([]*acknowledger, 0, len(s.outstanding))
+	for token := range s.outstanding {
+		tokens = append(tokens, token)
+	}
+	s.ackMu.Unlock()
+
+	if len(tokens) > 0 {
+		klog.V(3).Infof("Requeuing %d outstanding deliveries on %s\n", len(tokens), s.GetName())
+	}
+
+	for _, token := range tokens {
+		err := token.Nack(true)
+		if err != nil && err != ErrAlreadyAcknowledged {
+			klog.V(1).Infof("Nack outstanding %d failed. Err: %v\n", token.delivery.DeliveryTag, err)
+		}
+	}
+}
+
+/*
+	Expires every token the handler never resolved. Called once their channel is
+	gone, the broker has already requeued the deliveries.
+*/
+func (s *Subscriber) expireOutstanding() {
+	s.ackMu.Lock()
+	tokens := make([]*acknowledger, 0, len(s.outstanding))
+	for token := range s.outstanding {
+		tokens = append(tokens, token)
+	}
+	s.outstanding = make(map[*acknowledger]struct{})
+	s.ackMu.Unlock()
+
+	if len(tokens) > 0 {
+		klog.V(3).Infof("Expiring %d outstanding deliveries on %s\n", len(tokens), s.GetName())
+	}
+
+	for _, token := range tokens {
+		token.mu.Lock()
+		token.expired = true
+		token.mu.Unlock()
+	}
+}
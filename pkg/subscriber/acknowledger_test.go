@@ -0,0 +1,139 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Runs deliveries through a DeferredAck handler that keeps every token
+*/
+func newDeferredTokens(t *testing.T, ack *fakeAcknowledger, count int) (*Subscriber, []interfaces.Acknowledger) {
+	t.Helper()
+
+	tokens := make([]interfaces.Acknowledger, 0, count)
+	s := newTestSubscriber(t, interfaces.SubscriberOptions{DeferredAck: true}, func(delivery *interfaces.Delivery) (interfaces.AckOutcome, error) {
+		tokens = append(tokens, delivery.Acknowledger)
+		return interfaces.AckOutcomeDefault, nil
+	})
+
+	for i := 0; i < count; i++ {
+		d := amqp.Delivery{Acknowledger: ack, DeliveryTag: uint64(i + 1)}
+		s.processDelivery(&d, newDelivery(&d))
+	}
+	if len(ack.get()) != 0 {
+		t.Fatalf("deferred deliveries settled before the handler resolved them: %v", ack.get())
+	}
+	return s, tokens
+}
+
+func TestAcknowledgerResolvesOnce(t *testing.T) {
+	tests := []struct {
+		name   string
+		first  func(interfaces.Acknowledger) error
+		second func(interfaces.Acknowledger) error
+		call   ackCall
+	}{
+		{"ack twice", interfaces.Acknowledger.Ack, interfaces.Acknowledger.Ack, ackCall{"ack", 1, false, false}},
+		{"nack after ack", interfaces.Acknowledger.Ack, func(a interfaces.Acknowledger) error { return a.Nack(true) }, ackCall{"ack", 1, false, false}},
+		{"ack after nack", func(a interfaces.Acknowledger) error { return a.Nack(false) }, interfaces.Acknowledger.Ack, ackCall{"nack", 1, false, false}},
+		{"ack after reject", interfaces.Acknowledger.Reject, interfaces.Acknowledger.Ack, ackCall{"reject", 1, false, false}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ack := &fakeAcknowledger{}
+			s, tokens := newDeferredTokens(t, ack, 1)
+
+			err := tt.first(tokens[0])
+			if err != nil {
+				t.Fatalf("first resolve failed. Err: %v", err)
+			}
+			err = tt.second(tokens[0])
+			if err != ErrAlreadyAcknowledged {
+				t.Fatalf("second resolve err = %v, want ErrAlreadyAcknowledged", err)
+			}
+
+			calls := ack.get()
+			if len(calls) != 1 || calls[0] != tt.call {
+				t.Fatalf("settlements = %v, want %v", calls, tt.call)
+			}
+			if len(s.outstanding) != 0 {
+				t.Fatalf("%d tokens still outstanding", len(s.outstanding))
+			}
+		})
+	}
+}
+
+func TestAcknowledgerExpiresOnRecover(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	s, tokens := newDeferredTokens(t, ack, 3)
+
+	err := tokens[0].Ack()
+	if err != nil {
+		t.Fatalf("Ack failed. Err: %v", err)
+	}
+
+	// what Recover does once the old channel is gone
+	s.expireOutstanding()
+
+	for i, token := range tokens[1:] {
+		err = token.Ack()
+		if err != ErrAcknowledgerExpired {
+			t.Fatalf("token %d err = %v, want ErrAcknowledgerExpired", i+2, err)
+		}
+	}
+	err = tokens[0].Ack()
+	if err != ErrAlreadyAcknowledged {
+		t.Fatalf("resolved token err = %v, want ErrAlreadyAcknowledged", err)
+	}
+
+	// the broker already requeued the rest, nothing may reach the new channel
+	calls := ack.get()
+	if len(calls) != 1 || calls[0] != (ackCall{"ack", 1, false, false}) {
+		t.Fatalf("settlements = %v, want only the first ack", calls)
+	}
+	if len(s.outstanding) != 0 {
+		t.Fatalf("%d tokens still outstanding", len(s.outstanding))
+	}
+}
+
+func TestNackOutstandingOnTeardown(t *testing.T) {
+	ack := &fakeAcknowledger{}
+	s, tokens := newDeferredTokens(t, ack, 3)
+
+	err := tokens[1].Reject()
+	if err != nil {
+		t.Fatalf("Reject failed. Err: %v", err)
+	}
+
+	// what teardown does while the channel is still open
+	s.nackOutstanding()
+
+	ack.checkOncePerTag(t, 3)
+	for _, call := range ack.get() {
+		want := ackCall{"nack", call.tag, false, true}
+		if call.tag == 2 {
+			want = ackCall{"reject", 2, false, false}
+		}
+		if call != want {
+			t.Fatalf("delivery %d settled with %+v, want %+v", call.tag, call, want)
+		}
+	}
+
+	for i, token := range tokens {
+		err = token.Ack()
+		if err != ErrAlreadyAcknowledged {
+			t.Fatalf("token %d err = %v, want ErrAlreadyAcknowledged", i+1, err)
+		}
+	}
+	if len(s.outstanding) != 0 {
+		t.Fatalf("%d tokens still outstanding", len(s.outstanding))
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+func validateBatch(options *interfaces.SubscriberOptions) error {
+	if options.BatchHandler == nil {
+		return nil
+	}
+	if options.BatchSize < 0 || options.BatchTimeout < 0 {
+		return ErrInvalidBatch
+	}
+	if options.Concurrency > 1 || options.DeferredAck {
+		return ErrBatchOptions
+	}
+
+	batchSize := options.BatchSize
+	if batchSize == 0 {
+		batchSize = defaultBatchSize
+	}
+	// a batch can never fill up when the broker holds back the rest
+	if options.PrefetchCount > 0 && options.PrefetchCount < batchSize {
+		return ErrBatchPrefetch
+	}
+
+	return nil
+}
+
+/*
+	Collects deliveries until the batch is full or the timeout since the first
+	delivery of the batch expires, whichever comes first
+*/
+func (s *Subscriber) batchLoop(msgs <-chan amqp.Delivery, doneChan chan struct{}) {
+	defer close(doneChan)
+
+	batch := make([]work, 0, s.batchSize)
+
+	var timer *time.Timer
+	var timeout <-chan time.Time
+
+	flush := func() {
+		if timer != nil {
+			timer.Stop()
+			timer = nil
+			timeout = nil
+		}
+		if len(batch) > 0 {
+			s.processBatch(batch)
+			batch = make([]work, 0, s.batchSize)
+		}
+	}
+
+	for {
+		select {
+		case d, ok := <-msgs:
+			if !ok {
+				flush()
+				klog.V(5).Infof("Exiting Subscriber Loop\n")
+				return
+			}
+
+			delivery := s.receive(&d)
+			batch = append(batch, work{raw: d, delivery: delivery})
+
+			if len(batch) == 1 {
+				timer = time.NewTimer(s.batchTimeout)
+				timeout = timer.C
+			}
+			if len(batch) >= s.batchSize {
+				flush()
+			}
+		case <-timeout:
+			flush()
+		}
+	}
+}
+
+/*
+	Runs the batch handler and settles every delivery in the batch. When the whole
+	batch is acked a single multiple ack covers it.
+*/
+func (s *Subscriber) processBatch(batch []work) {
+	klog.V(5).Infof("Processing batch of %d on %s\n", len(batch), s.GetName())
+
+	if s.breaker != nil && !s.breaker.Allow() {
+		for i := range batch {
+			s.holdBack(&batch[i].raw)
+		}
+		return
+	}
+
+	readable := batch[:0]
+	for _, w := range batch {
+		err := s.unwrap(w.delivery)
+		if err != nil {
+			klog.V(1).Infof("unwrap %d failed. Err: %v\n", w.raw.DeliveryTag, err)
+			s.discardUnreadable(&w.raw, w.delivery, err)
+			continue
+		}
+		readable = append(readable, w)
+	}
+	batch = readable
+	if len(batch) == 0 {
+		return
+	}
+
+	deliveries := make([]*interfaces.Delivery, len(batch))
+	for i := range batch {
+		deliveries[i] = batch[i].delivery
+	}
+
+	outcomes, err := s.batchHandler.ProcessBatch(deliveries)
+	if err != nil {
+		klog.V(1).Infof("ProcessBatch() failed. Err: %v\n", err)
+	}
+	if s.breaker != nil {
+		s.breaker.Record(err)
+	}
+	if len(outcomes) > 0 && len(outcomes) != len(batch) {
+		klog.V(1).Infof("ProcessBatch() returned %d outcomes for %d deliveries\n", len(outcomes), len(batch))
+		outcomes = nil
+		if err == nil {
+			err = ErrBatchOutcomeMismatch
+		}
+	}
+
+	resolved := make([]interfaces.AckOutcome, len(batch))
+	allAcked := true
+	for i := range batch {
+		outcome := interfaces.AckOutcomeDefault
+		if len(outcomes) > 0 {
+			outcome = outcomes[i]
+		}
+		resolved[i] = s.resolveOutcome(&batch[i].raw, outcome, err)
+		if resolved[i] != interfaces.AckOutcomeAck {
+			allAcked = false
+		}
+	}
+
+	// nothing else is unacked on this channel, so one multiple ack settles the batch
+	if allAcked && !s.options.NoAck && !s.stream {
+		err = batch[len(batch)-1].raw.Ack(true)
+		if err != nil {
+			klog.V(1).Infof("Ack(multiple) failed. Err: %v\n", err)
+			return
+		}
+		for i := range batch {
+			s.stats.countOutcome(interfaces.AckOutcomeAck)
+			s.releaseClaim(&batch[i].raw)
+		}
+		return
+	}
+
+	for i := range batch {
+		err = s.complete(&batch[i].raw, resolved[i])
+		if err != nil {
+			klog.V(1).Infof("complete() failed. Err: %v\n", err)
+		}
+	}
+}
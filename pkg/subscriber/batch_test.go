@@ -0,0 +1,204 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+type batchHandlerFunc func(deliveries []*interfaces.Delivery) ([]interfaces.AckOutcome, error)
+
+func (f batchHandlerFunc) ProcessBatch(deliveries []*interfaces.Delivery) ([]interfaces.AckOutcome, error) {
+	return f(deliveries)
+}
+
+func newBatchSubscriber(t *testing.T, size int, timeout time.Duration, handler batchHandlerFunc) *Subscriber {
+	t.Helper()
+
+	var batchHandler interfaces.RabbitBatchHandler = handler
+	s, err := New(SubscriberOptions{
+		SubscriberOptions: &interfaces.SubscriberOptions{
+			Name:         "test",
+			BatchHandler: &batchHandler,
+			BatchSize:    size,
+			BatchTimeout: timeout,
+		},
+	})
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+	return s
+}
+
+/*
+	Records the delivery tags of every batch the handler gets
+*/
+type batchRecorder struct {
+	batches [][]uint64
+	mu      sync.Mutex
+}
+
+func (r *batchRecorder) add(deliveries []*interfaces.Delivery) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tags := make([]uint64, len(deliveries))
+	for i, delivery := range deliveries {
+		tags[i] = delivery.DeliveryTag
+	}
+	r.batches = append(r.batches, tags)
+}
+
+func (r *batchRecorder) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	sizes := make([]int, len(r.batches))
+	for i, batch := range r.batches {
+		sizes[i] = len(batch)
+	}
+	return sizes
+}
+
+func TestBatchFlushesOnSize(t *testing.T) {
+	recorder := &batchRecorder{}
+	s := newBatchSubscriber(t, 3, time.Hour, func(deliveries []*interfaces.Delivery) ([]interfaces.AckOutcome, error) {
+		recorder.add(deliveries)
+		return nil, nil
+	})
+
+	ack := &fakeAcknowledger{}
+	s.batchLoop(newDeliveries(ack, "", "", "", "", "", "", ""), make(chan struct{}))
+
+	// the last, partial batch is flushed when the consumer is cancelled
+	sizes := recorder.sizes()
+	if len(sizes) != 3 || sizes[0] != 3 || sizes[1] != 3 || sizes[2] != 1 {
+		t.Fatalf("batch sizes = %v, want [3 3 1]", sizes)
+	}
+
+	calls := ack.get()
+	want := []ackCall{{"ack", 3, true, false}, {"ack", 6, true, false}, {"ack", 7, true, false}}
+	if len(calls) != len(want) {
+		t.Fatalf("acks = %v, want %v", calls, want)
+	}
+	for i := range want {
+		if calls[i] != want[i] {
+			t.Fatalf("acks = %v, want %v", calls, want)
+		}
+	}
+}
+
+func TestBatchFlushesOnTimeout(t *testing.T) {
+	recorder := &batchRecorder{}
+	flushed := make(chan struct{}, 1)
+	s := newBatchSubscriber(t, 10, 20*time.Millisecond, func(deliveries []*interfaces.Delivery) ([]interfaces.AckOutcome, error) {
+		recorder.add(deliveries)
+		flushed <- struct{}{}
+		return nil, nil
+	})
+
+	ack := &fakeAcknowledger{}
+	msgs := make(chan amqp.Delivery)
+	doneChan := make(chan struct{})
+	go s.batchLoop(msgs, doneChan)
+
+	msgs <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 1}
+	msgs <- amqp.Delivery{Acknowledger: ack, DeliveryTag: 2}
+
+	select {
+	case <-flushed:
+	case <-time.After(time.Second):
+		t.Fatalf("batch was not flushed after the timeout")
+	}
+
+	close(msgs)
+	<-doneChan
+
+	sizes := recorder.sizes()
+	if len(sizes) != 1 || sizes[0] != 2 {
+		t.Fatalf("batch sizes = %v, want [2]", sizes)
+	}
+	calls := ack.get()
+	if len(calls) != 1 || calls[0] != (ackCall{"ack", 2, true, false}) {
+		t.Fatalf("acks = %v, want a single multiple ack of 2", calls)
+	}
+}
+
+func TestBatchOutcomes(t *testing.T) {
+	errBatch := errors.New("batch failed")
+
+	tests := []struct {
+		name        string
+		redelivered bool
+		outcomes    []interfaces.AckOutcome
+		err         error
+		want        []ackCall
+	}{
+		{
+			name:     "per message outcomes",
+			outcomes: []interfaces.AckOutcome{interfaces.AckOutcomeAck, interfaces.AckOutcomeNackRequeue, interfaces.AckOutcomeReject},
+			want:     []ackCall{{"ack", 1, false, false}, {"nack", 2, false, true}, {"reject", 3, false, false}},
+		},
+		{
+			name:     "explicit acks",
+			outcomes: []interfaces.AckOutcome{interfaces.AckOutcomeAck, interfaces.AckOutcomeAck, interfaces.AckOutcomeAck},
+			want:     []ackCall{{"ack", 3, true, false}},
+		},
+		{
+			name: "error requeues the batch",
+			err:  errBatch,
+			want: []ackCall{{"nack", 1, false, true}, {"nack", 2, false, true}, {"nack", 3, false, true}},
+		},
+		{
+			name:        "error on a redelivered batch discards it",
+			redelivered: true,
+			err:         errBatch,
+			want:        []ackCall{{"nack", 1, false, false}, {"nack", 2, false, false}, {"nack", 3, false, false}},
+		},
+		{
+			name:     "outcome count mismatch fails the batch",
+			outcomes: []interfaces.AckOutcome{interfaces.AckOutcomeAck},
+			want:     []ackCall{{"nack", 1, false, true}, {"nack", 2, false, true}, {"nack", 3, false, true}},
+		},
+		{
+			name:     "outcomes override the error",
+			outcomes: []interfaces.AckOutcome{interfaces.AckOutcomeAck, interfaces.AckOutcomeDefault, interfaces.AckOutcomeReject},
+			err:      errBatch,
+			want:     []ackCall{{"ack", 1, false, false}, {"nack", 2, false, true}, {"reject", 3, false, false}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newBatchSubscriber(t, 3, time.Hour, func(deliveries []*interfaces.Delivery) ([]interfaces.AckOutcome, error) {
+				return tt.outcomes, tt.err
+			})
+
+			ack := &fakeAcknowledger{}
+			batch := make([]work, 3)
+			for i := range batch {
+				batch[i].raw = amqp.Delivery{Acknowledger: ack, DeliveryTag: uint64(i + 1), Redelivered: tt.redelivered}
+				batch[i].delivery = newDelivery(&batch[i].raw)
+			}
+			s.processBatch(batch)
+
+			calls := ack.get()
+			if len(calls) != len(tt.want) {
+				t.Fatalf("settlements = %v, want %v", calls, tt.want)
+			}
+			for i := range tt.want {
+				if calls[i] != tt.want[i] {
+					t.Fatalf("settlements = %v, want %v", calls, tt.want)
+				}
+			}
+		})
+	}
+}
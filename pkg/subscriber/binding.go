@@ -0,0 +1,83 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"fmt"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Builds the queue bindings for the exchange. Headers exchanges are bound once per
+	header binding, every other exchange once per binding key. Without either the
+	queue is bound with the empty key.
+*/
+func queueBindings(options *interfaces.SubscriberOptions) []binding {
+	bindings := make([]binding, 0)
+
+	for _, headerBinding := range options.HeaderBindings {
+		args := make(amqp.Table)
+		for key, value := range headerBinding.Headers {
+			args[key] = value
+		}
+		args[common.ArgMatch] = common.HeaderMatchTypeToString(headerBinding.Match)
+
+		bindings = append(bindings, binding{
+			key:  "",
+			args: args,
+		})
+	}
+
+	for _, key := range options.BindingKeys {
+		bindings = append(bindings, binding{
+			key: key,
+		})
+	}
+
+	if len(bindings) == 0 {
+		bindings = append(bindings, binding{
+			key: "",
+		})
+	}
+
+	return bindings
+}
+
+func validateBindings(options *interfaces.SubscriberOptions) error {
+	if options.Type == interfaces.ExchangeTypeHeaders {
+		if len(options.BindingKeys) > 0 {
+			return ErrBindingKeyExchangeType
+		}
+	} else if len(options.HeaderBindings) > 0 {
+		return ErrHeaderBindingExchangeType
+	}
+
+	for _, key := range options.BindingKeys {
+		err := common.ValidateBindingKey(options.Type, key)
+		if err != nil {
+			return fmt.Errorf("%w: %s", err, key)
+		}
+	}
+
+	for _, headerBinding := range options.HeaderBindings {
+		if headerBinding.Match != interfaces.HeaderMatchTypeAll && headerBinding.Match != interfaces.HeaderMatchTypeAny {
+			return ErrInvalidHeaderBinding
+		}
+		if err := headerBinding.Headers.Validate(); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidHeaderBinding, err)
+		}
+		for key := range headerBinding.Headers {
+			if strings.HasPrefix(key, common.ReservedHeaderPrefix) {
+				return fmt.Errorf("%w: %s is ignored when matching", ErrInvalidHeaderBinding, key)
+			}
+		}
+	}
+
+	return nil
+}
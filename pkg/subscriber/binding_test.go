@@ -0,0 +1,117 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"errors"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+func TestQueueBindings(t *testing.T) {
+	tests := []struct {
+		name    string
+		options interfaces.SubscriberOptions
+		want    []binding
+	}{
+		{
+			name: "no bindings",
+			want: []binding{{key: ""}},
+		},
+		{
+			name:    "multiple keys",
+			options: interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeTopic, BindingKeys: []string{"orders.*", "refunds.#"}},
+			want:    []binding{{key: "orders.*"}, {key: "refunds.#"}},
+		},
+		{
+			name: "headers all",
+			options: interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeHeaders, HeaderBindings: []interfaces.HeaderBinding{
+				{Match: interfaces.HeaderMatchTypeAll, Headers: amqp.Table{"region": "eu", "kind": "order"}},
+			}},
+			want: []binding{{key: "", args: amqp.Table{"region": "eu", "kind": "order", common.ArgMatch: common.HeaderMatchAll}}},
+		},
+		{
+			name: "multiple header bindings",
+			options: interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeHeaders, HeaderBindings: []interfaces.HeaderBinding{
+				{Match: interfaces.HeaderMatchTypeAny, Headers: amqp.Table{"region": "eu", "priority": int32(1)}},
+				{Match: interfaces.HeaderMatchTypeAll, Headers: amqp.Table{"region": "us"}},
+			}},
+			want: []binding{
+				{key: "", args: amqp.Table{"region": "eu", "priority": int32(1), common.ArgMatch: common.HeaderMatchAny}},
+				{key: "", args: amqp.Table{"region": "us", common.ArgMatch: common.HeaderMatchAll}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			bindings := queueBindings(&options)
+			if len(bindings) != len(tt.want) {
+				t.Fatalf("queueBindings() = %v, want %v", bindings, tt.want)
+			}
+			for i, want := range tt.want {
+				if bindings[i].key != want.key || len(bindings[i].args) != len(want.args) {
+					t.Fatalf("binding %d = %v, want %v", i, bindings[i], want)
+				}
+				for key, value := range want.args {
+					if bindings[i].args[key] != value {
+						t.Fatalf("binding %d = %v, want %v", i, bindings[i], want)
+					}
+				}
+			}
+		})
+	}
+
+	// the caller's table is copied, not extended with x-match
+	headers := amqp.Table{"region": "eu"}
+	queueBindings(&interfaces.SubscriberOptions{HeaderBindings: []interfaces.HeaderBinding{{Headers: headers}}})
+	if len(headers) != 1 {
+		t.Fatalf("queueBindings changed the header binding: %v", headers)
+	}
+}
+
+func TestValidateBindings(t *testing.T) {
+	tests := []struct {
+		name    string
+		options interfaces.SubscriberOptions
+		err     error
+	}{
+		{"no bindings", interfaces.SubscriberOptions{}, nil},
+		{"topic keys", interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeTopic, BindingKeys: []string{"orders.*", "#"}}, nil},
+		{"invalid topic key", interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeTopic, BindingKeys: []string{"orders.*", "orders*"}}, common.ErrInvalidBindingKey},
+		{"direct keys are literal", interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeDirect, BindingKeys: []string{"orders*"}}, nil},
+		{"keys on a headers exchange", interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeHeaders, BindingKeys: []string{"orders"}}, ErrBindingKeyExchangeType},
+		{"header bindings on a topic exchange", interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeTopic, HeaderBindings: []interfaces.HeaderBinding{{Headers: amqp.Table{"region": "eu"}}}}, ErrHeaderBindingExchangeType},
+		{"multiple header bindings", interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeHeaders, HeaderBindings: []interfaces.HeaderBinding{
+			{Match: interfaces.HeaderMatchTypeAll, Headers: amqp.Table{"region": "eu"}},
+			{Match: interfaces.HeaderMatchTypeAny, Headers: amqp.Table{"region": "us", "kind": "order"}},
+		}}, nil},
+		{"unknown match", interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeHeaders, HeaderBindings: []interfaces.HeaderBinding{{Match: 7}}}, ErrInvalidHeaderBinding},
+		{"x-match in the headers", interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeHeaders, HeaderBindings: []interfaces.HeaderBinding{
+			{Match: interfaces.HeaderMatchTypeAny, Headers: amqp.Table{common.ArgMatch: common.HeaderMatchAll}},
+		}}, ErrInvalidHeaderBinding},
+		{"reserved header", interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeHeaders, HeaderBindings: []interfaces.HeaderBinding{
+			{Match: interfaces.HeaderMatchTypeAll, Headers: amqp.Table{"region": "eu"}},
+			{Match: interfaces.HeaderMatchTypeAll, Headers: amqp.Table{"x-region": "eu"}},
+		}}, ErrInvalidHeaderBinding},
+		{"unencodable header", interfaces.SubscriberOptions{Type: interfaces.ExchangeTypeHeaders, HeaderBindings: []interfaces.HeaderBinding{
+			{Match: interfaces.HeaderMatchTypeAll, Headers: amqp.Table{"region": struct{}{}}},
+		}}, ErrInvalidHeaderBinding},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			err := validateBindings(&options)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("validateBindings() err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Stops consuming when the breaker opens and starts again once it lets probes
+	through, then tells the application
+*/
+func (s *Subscriber) circuitChanged(from, to interfaces.CircuitState) {
+	switch to {
+	case interfaces.CircuitStateOpen:
+		// the breaker can open from the message loop, which pausing waits on
+		go s.pause()
+	case interfaces.CircuitStateHalfOpen:
+		s.resume()
+	}
+
+	if s.options.BreakerHandler != nil {
+		(*s.options.BreakerHandler).CircuitStateChanged(s.GetName(), from, to)
+	}
+}
+
+/*
+	Cancels the consumer so the queue holds on to the messages while the breaker
+	is open
+*/
+func (s *Subscriber) pause() {
+	s.consumeMu.Lock()
+	defer s.consumeMu.Unlock()
+
+	if !s.running || s.breaker.State() != interfaces.CircuitStateOpen {
+		return
+	}
+
+	klog.V(3).Infof("Circuit open, pausing %s\n", s.GetName())
+	err := s.stopConsuming()
+	if err != nil {
+		klog.V(1).Infof("stopConsuming %s failed. Err: %v\n", s.GetName(), err)
+	}
+	s.running = false
+	s.paused = true
+}
+
+/*
+	Starts consuming again so the half-open breaker can probe the handler
+*/
+func (s *Subscriber) resume() {
+	s.consumeMu.Lock()
+	defer s.consumeMu.Unlock()
+
+	if !s.paused || s.breaker.State() == interfaces.CircuitStateOpen {
+		return
+	}
+
+	klog.V(3).Infof("Circuit half-open, resuming %s\n", s.GetName())
+	err := s.startConsuming()
+	if err != nil {
+		// a failed restart counts as a failed probe and opens the breaker again
+		klog.V(1).Infof("startConsuming %s failed. Err: %v\n", s.GetName(), err)
+		go s.breaker.Record(err)
+		return
+	}
+	s.running = true
+	s.paused = false
+}
+
+/*
+	Hands a delivery back to the queue without running the handler. Deliveries
+	already prefetched when the breaker opened end up here.
+*/
+func (s *Subscriber) holdBack(d *amqp.Delivery) {
+	klog.V(4).Infof("Circuit open, requeuing %d on %s\n", d.DeliveryTag, s.GetName())
+
+	// stream messages stay in flight so their offset is not committed
+	sent, err := s.acknowledge(d, interfaces.AckOutcomeNackRequeue)
+	if err != nil {
+		klog.V(1).Infof("acknowledge() failed. Err: %v\n", err)
+		return
+	}
+	s.stats.countOutcome(sent)
+}
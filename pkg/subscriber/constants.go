@@ -0,0 +1,129 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	// maximum length of a queue name
+	maxQueueNameLength int = 255
+
+	// reserved queue name prefix
+	reservedQueuePrefix string = "amq."
+
+	// batch defaults
+	defaultBatchSize    int           = 100
+	defaultBatchTimeout time.Duration = time.Second
+
+	// streams require a prefetch and manual acks to grant consumer credit
+	defaultStreamPrefetch int = 100
+
+	// stream offsets are stored every so many messages or this often, whichever is first
+	defaultOffsetCommitCount    int           = 100
+	defaultOffsetCommitInterval time.Duration = time.Second
+)
+
+var (
+	// ErrHandlerNotFound a message, delivery, ack or batch handler is required
+	ErrHandlerNotFound = errors.New("a message, delivery, ack or batch handler is required")
+
+	// ErrMultipleHandlers only one of message, delivery, ack or batch handler can be set
+	ErrMultipleHandlers = errors.New("only one of message, delivery, ack or batch handler can be set")
+
+	// ErrInvalidBatch the batch size and timeout cannot be negative
+	ErrInvalidBatch = errors.New("the batch size and timeout cannot be negative")
+
+	// ErrBatchOptions batches cannot be combined with concurrency or deferred acks
+	ErrBatchOptions = errors.New("batches cannot be combined with concurrency or deferred acks")
+
+	// ErrBatchPrefetch the prefetch count is smaller than the batch size
+	ErrBatchPrefetch = errors.New("the prefetch count is smaller than the batch size")
+
+	// ErrBatchOutcomeMismatch the batch handler returned the wrong number of outcomes
+	ErrBatchOutcomeMismatch = errors.New("the batch handler returned the wrong number of outcomes")
+
+	// ErrDeferredAckNoAck deferred acknowledgement requires manual acks
+	ErrDeferredAckNoAck = errors.New("deferred acknowledgement requires manual acks")
+
+	// ErrAlreadyAcknowledged the delivery has already been acknowledged
+	ErrAlreadyAcknowledged = errors.New("the delivery has already been acknowledged")
+
+	// ErrInvalidMaxDecompressedSize the maximum decompressed size cannot be negative
+	ErrInvalidMaxDecompressedSize = errors.New("the maximum decompressed size cannot be negative")
+
+	// ErrRequireEncryptionKeyProvider RequireEncryption needs a KeyProvider
+	ErrRequireEncryptionKeyProvider = errors.New("RequireEncryption needs a KeyProvider")
+
+	// ErrInvalidReplayWindow the replay window cannot be negative
+	ErrInvalidReplayWindow = errors.New("the replay window cannot be negative")
+
+	// ErrReplayWindowVerifier ReplayWindow needs a Verifier
+	ErrReplayWindowVerifier = errors.New("ReplayWindow needs a Verifier")
+
+	// ErrInvalidReplayCacheSize the replay cache size cannot be negative
+	ErrInvalidReplayCacheSize = errors.New("the replay cache size cannot be negative")
+
+	// ErrRequiredSignedHeadersVerifier RequiredSignedHeaders needs a Verifier
+	ErrRequiredSignedHeadersVerifier = errors.New("RequiredSignedHeaders needs a Verifier")
+
+	// ErrBreakerNoAck the circuit breaker requires acknowledgements to hold messages back
+	ErrBreakerNoAck = errors.New("the circuit breaker requires acknowledgements to hold messages back")
+
+	// ErrAcknowledgerExpired the channel of the delivery closed and the broker requeued it
+	ErrAcknowledgerExpired = errors.New("the channel of the delivery closed and the broker requeued it")
+
+	// ErrInvalidOffsetCommit the offset commit count and interval cannot be negative
+	ErrInvalidOffsetCommit = errors.New("the offset commit count and interval cannot be negative")
+
+	// ErrInvalidPrefetch the prefetch count and size cannot be negative
+	ErrInvalidPrefetch = errors.New("the prefetch count and size cannot be negative")
+
+	// ErrStreamPrefetchDeferredAck the prefetch of a running stream consumer with deferred acks cannot change
+	ErrStreamPrefetchDeferredAck = errors.New("the prefetch of a running stream consumer with deferred acks cannot change")
+
+	// ErrInvalidConcurrency the concurrency cannot be negative
+	ErrInvalidConcurrency = errors.New("the concurrency cannot be negative")
+
+	// ErrInvalidQueueName the queue name is too long or uses a reserved prefix
+	ErrInvalidQueueName = errors.New("the queue name is too long or uses a reserved prefix")
+
+	// ErrHeaderBindingExchangeType header bindings only apply to headers exchanges
+	ErrHeaderBindingExchangeType = errors.New("header bindings only apply to headers exchanges")
+
+	// ErrBindingKeyExchangeType headers exchanges route on headers instead of binding keys
+	ErrBindingKeyExchangeType = errors.New("headers exchanges route on headers instead of binding keys")
+
+	// ErrInvalidHeaderBinding the header binding is invalid
+	ErrInvalidHeaderBinding = errors.New("the header binding is invalid")
+
+	// ErrInvalidQueueType the queue type is not supported
+	ErrInvalidQueueType = errors.New("the queue type is not supported")
+
+	// ErrInvalidQueueArgument a queue argument has an invalid value
+	ErrInvalidQueueArgument = errors.New("a queue argument has an invalid value")
+
+	// ErrUnsupportedQueueArgument the queue argument is not supported by the queue type
+	ErrUnsupportedQueueArgument = errors.New("the queue argument is not supported by the queue type")
+
+	// ErrExclusiveQueueType only classic queues can be exclusive
+	ErrExclusiveQueueType = errors.New("only classic queues can be exclusive")
+
+	// ErrNonDurableQueueType quorum and stream queues must be durable
+	ErrNonDurableQueueType = errors.New("quorum and stream queues must be durable")
+
+	// ErrAutoDeleteQueueType quorum and stream queues cannot be auto-deleted
+	ErrAutoDeleteQueueType = errors.New("quorum and stream queues cannot be auto-deleted")
+
+	// ErrStreamRequiresAck stream queues cannot be consumed with no-ack
+	ErrStreamRequiresAck = errors.New("stream queues cannot be consumed with no-ack")
+
+	// ErrStreamOffsetQueueType stream offsets only apply to stream queues
+	ErrStreamOffsetQueueType = errors.New("stream offsets only apply to stream queues")
+
+	// ErrInvalidStreamOffset the stream offset is invalid
+	ErrInvalidStreamOffset = errors.New("the stream offset is invalid")
+)
@@ -0,0 +1,206 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Applies the prefetch and starts a consumer along with its message loop
+*/
+func (s *Subscriber) startConsuming() error {
+	err := s.applyQos()
+	if err != nil {
+		klog.V(1).Infof("applyQos %s failed. Err: %v\n", s.GetName(), err)
+		return err
+	}
+
+	var consumeArgs amqp.Table
+	if s.stream {
+		consumeArgs, err = s.streamArguments()
+		if err != nil {
+			klog.V(1).Infof("streamArguments %s failed. Err: %v\n", s.GetName(), err)
+			return err
+		}
+	}
+	s.consumerTag, err = common.NewConsumerTag(s.GetName())
+	if err != nil {
+		klog.V(1).Infof("NewConsumerTag %s failed. Err: %v\n", s.GetName(), err)
+		return err
+	}
+
+	klog.V(3).Infof("Consume: %s\n", s.GetName())
+	msgs, err := s.channel.Consume(
+		s.queue.Name,        // queue
+		s.consumerTag,       // consumer tag
+		s.options.NoLocal,   // no local
+		s.options.NoAck,     // no ack
+		s.options.Exclusive, // exclusive
+		s.options.NoWait,    // no wait
+		consumeArgs,         // args
+	)
+	if err != nil {
+		klog.V(1).Infof("Consume %s failed. Err: %v\n", s.GetName(), err)
+		return err
+	}
+
+	klog.V(3).Infof("Subscriber Running message loop...\n")
+	s.throttleCtx, s.stopThrottle = context.WithCancel(context.Background())
+	s.doneChan = make(chan struct{})
+	if s.batchHandler != nil {
+		go s.batchLoop(msgs, s.doneChan)
+	} else if s.options.Concurrency > 1 {
+		go s.dispatchLoop(msgs, s.doneChan)
+	} else {
+		go s.consumeLoop(msgs, s.doneChan)
+	}
+
+	return nil
+}
+
+/*
+	Counts the delivery and registers it before it is handed to the handler
+*/
+func (s *Subscriber) receive(d *amqp.Delivery) *interfaces.Delivery {
+	atomic.AddUint64(&s.stats.received, 1)
+
+	s.throttle(len(d.Body))
+
+	if s.stream {
+		s.beginOffset(d)
+	}
+
+	return newDelivery(d)
+}
+
+/*
+	Processes deliveries one at a time until the consumer is cancelled or the
+	channel closes
+*/
+func (s *Subscriber) consumeLoop(msgs <-chan amqp.Delivery, doneChan chan struct{}) {
+	defer close(doneChan)
+
+	for d := range msgs {
+		delivery := s.receive(&d)
+		s.processDelivery(&d, delivery)
+	}
+
+	klog.V(5).Infof("Exiting Subscriber Loop\n")
+}
+
+type work struct {
+	raw      amqp.Delivery
+	delivery *interfaces.Delivery
+}
+
+/*
+	Dispatches deliveries to a pool of workers. Deliveries with an ordering key
+	always go to the same worker so they are processed in order, the rest go to
+	whichever worker is free.
+*/
+func (s *Subscriber) dispatchLoop(msgs <-chan amqp.Delivery, doneChan chan struct{}) {
+	defer close(doneChan)
+
+	concurrency := s.options.Concurrency
+	shared := make(chan work)
+	keyed := make([]chan work, concurrency)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		keyed[i] = make(chan work)
+
+		wg.Add(1)
+		go func(own chan work) {
+			defer wg.Done()
+			s.worker(own, shared)
+		}(keyed[i])
+	}
+
+	for d := range msgs {
+		delivery := s.receive(&d)
+
+		key := ""
+		if s.options.OrderingKey != nil {
+			key = s.options.OrderingKey(delivery)
+		}
+
+		if key == "" {
+			shared <- work{raw: d, delivery: delivery}
+			continue
+		}
+
+		hash := fnv.New32a()
+		hash.Write([]byte(key))
+		keyed[hash.Sum32()%uint32(concurrency)] <- work{raw: d, delivery: delivery}
+	}
+
+	close(shared)
+	for _, own := range keyed {
+		close(own)
+	}
+	wg.Wait()
+
+	klog.V(5).Infof("Exiting Subscriber Loop\n")
+}
+
+func (s *Subscriber) worker(own <-chan work, shared <-chan work) {
+	for own != nil || shared != nil {
+		select {
+		case w, ok := <-own:
+			if !ok {
+				own = nil
+				continue
+			}
+			s.processDelivery(&w.raw, w.delivery)
+		case w, ok := <-shared:
+			if !ok {
+				shared = nil
+				continue
+			}
+			s.processDelivery(&w.raw, w.delivery)
+		}
+	}
+}
+
+/*
+	Cancels the consumer and waits for the message loop to finish the deliveries
+	it is working on. Unacked deliveries stay on the channel and can still be settled.
+*/
+func (s *Subscriber) stopConsuming() error {
+	var retErr error
+
+	// don't keep the loop waiting on the rate limit
+	if s.stopThrottle != nil {
+		s.stopThrottle()
+	}
+
+	err := s.channel.Cancel(s.consumerTag, false)
+	if err != nil {
+		// a closed channel has already closed the deliveries
+		klog.V(1).Infof("Cancel %s failed. Err: %v\n", s.consumerTag, err)
+		retErr = err
+	}
+
+	if s.doneChan != nil {
+		<-s.doneChan
+		s.doneChan = nil
+	}
+
+	// a restart resumes from the stored offset
+	if s.stream {
+		s.flushOffset()
+	}
+
+	return retErr
+}
@@ -0,0 +1,255 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+type ackCall struct {
+	method   string
+	tag      uint64
+	multiple bool
+	requeue  bool
+}
+
+/*
+	Records the acknowledgements the subscriber sends instead of a channel
+*/
+type fakeAcknowledger struct {
+	calls []ackCall
+	mu    sync.Mutex
+}
+
+func (a *fakeAcknowledger) record(call ackCall) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.calls = append(a.calls, call)
+	return nil
+}
+
+func (a *fakeAcknowledger) Ack(tag uint64, multiple bool) error {
+	return a.record(ackCall{"ack", tag, multiple, false})
+}
+
+func (a *fakeAcknowledger) Nack(tag uint64, multiple bool, requeue bool) error {
+	return a.record(ackCall{"nack", tag, multiple, requeue})
+}
+
+func (a *fakeAcknowledger) Reject(tag uint64, requeue bool) error {
+	return a.record(ackCall{"reject", tag, false, requeue})
+}
+
+func (a *fakeAcknowledger) get() []ackCall {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	return append([]ackCall{}, a.calls...)
+}
+
+/*
+	Fails unless every delivery tag from 1 to count was settled exactly once
+*/
+func (a *fakeAcknowledger) checkOncePerTag(t *testing.T, count int) {
+	t.Helper()
+
+	seen := make(map[uint64]int)
+	for _, call := range a.get() {
+		seen[call.tag]++
+	}
+	for tag := uint64(1); tag <= uint64(count); tag++ {
+		if seen[tag] != 1 {
+			t.Fatalf("delivery %d settled %d times: %v", tag, seen[tag], a.get())
+		}
+	}
+	if len(seen) != count {
+		t.Fatalf("settled %d deliveries, want %d", len(seen), count)
+	}
+}
+
+type ackHandlerFunc func(delivery *interfaces.Delivery) (interfaces.AckOutcome, error)
+
+func (f ackHandlerFunc) HandleDelivery(delivery *interfaces.Delivery) (interfaces.AckOutcome, error) {
+	return f(delivery)
+}
+
+func newTestSubscriber(t *testing.T, options interfaces.SubscriberOptions, handler ackHandlerFunc) *Subscriber {
+	t.Helper()
+
+	var ackHandler interfaces.RabbitAckHandler = handler
+	options.Name = "test"
+	options.AckHandler = &ackHandler
+
+	s, err := New(SubscriberOptions{SubscriberOptions: &options})
+	if err != nil {
+		t.Fatalf("New failed. Err: %v", err)
+	}
+	return s
+}
+
+/*
+	Queues a delivery per key, tagged from 1 in order, and closes the channel as
+	a cancelled consumer would
+*/
+func newDeliveries(ack amqp.Acknowledger, keys ...string) chan amqp.Delivery {
+	msgs := make(chan amqp.Delivery, len(keys))
+	for i, key := range keys {
+		msgs <- amqp.Delivery{
+			Acknowledger: ack,
+			DeliveryTag:  uint64(i + 1),
+			Headers:      amqp.Table{"key": key},
+			Body:         []byte(fmt.Sprintf("%d", i+1)),
+		}
+	}
+	close(msgs)
+	return msgs
+}
+
+func headerKey(delivery *interfaces.Delivery) string {
+	key, _ := delivery.Headers["key"].(string)
+	return key
+}
+
+/*
+	Returns keys that the dispatcher sends to different workers
+*/
+func distinctWorkerKeys(concurrency, count int) []string {
+	keys := make([]string, 0, count)
+	used := make(map[uint32]bool)
+	for i := 0; len(keys) < count; i++ {
+		key := fmt.Sprintf("key-%d", i)
+
+		hash := fnv.New32a()
+		hash.Write([]byte(key))
+		worker := hash.Sum32() % uint32(concurrency)
+		if !used[worker] {
+			used[worker] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func TestDispatchKeepsKeyOrder(t *testing.T) {
+	var mu sync.Mutex
+	processed := make(map[string][]uint64)
+
+	s := newTestSubscriber(t, interfaces.SubscriberOptions{Concurrency: 4, OrderingKey: headerKey}, func(delivery *interfaces.Delivery) (interfaces.AckOutcome, error) {
+		// uneven handler times give later deliveries a chance to overtake
+		time.Sleep(time.Duration(delivery.DeliveryTag%3) * time.Millisecond)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		key := headerKey(delivery)
+		processed[key] = append(processed[key], delivery.DeliveryTag)
+		return interfaces.AckOutcomeDefault, nil
+	})
+
+	keys := make([]string, 0)
+	for i := 0; i < 10; i++ {
+		keys = append(keys, "a", "b", "c")
+	}
+	ack := &fakeAcknowledger{}
+	s.dispatchLoop(newDeliveries(ack, keys...), make(chan struct{}))
+
+	for key, tags := range processed {
+		if len(tags) != 10 {
+			t.Fatalf("key %s processed %d deliveries, want 10", key, len(tags))
+		}
+		for i := 1; i < len(tags); i++ {
+			if tags[i] < tags[i-1] {
+				t.Fatalf("key %s processed out of order: %v", key, tags)
+			}
+		}
+	}
+
+	ack.checkOncePerTag(t, len(keys))
+	for _, call := range ack.get() {
+		if call.method != "ack" || call.multiple {
+			t.Fatalf("unexpected settlement %+v", call)
+		}
+	}
+}
+
+/*
+	Every delivery waits in the handler until all of them are in it, which only
+	happens when they run in parallel
+*/
+func TestDispatchRunsInParallel(t *testing.T) {
+	const concurrency = 4
+
+	tests := []struct {
+		name string
+		keys []string
+	}{
+		{"different keys", distinctWorkerKeys(concurrency, concurrency)},
+		{"without keys", []string{"", "", "", ""}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var arrived sync.WaitGroup
+			arrived.Add(len(tt.keys))
+			allArrived := make(chan struct{})
+			go func() {
+				arrived.Wait()
+				close(allArrived)
+			}()
+
+			s := newTestSubscriber(t, interfaces.SubscriberOptions{Concurrency: concurrency, OrderingKey: headerKey}, func(delivery *interfaces.Delivery) (interfaces.AckOutcome, error) {
+				arrived.Done()
+				select {
+				case <-allArrived:
+					return interfaces.AckOutcomeDefault, nil
+				case <-time.After(time.Second):
+					return interfaces.AckOutcomeDefault, fmt.Errorf("delivery %d ran alone", delivery.DeliveryTag)
+				}
+			})
+
+			ack := &fakeAcknowledger{}
+			s.dispatchLoop(newDeliveries(ack, tt.keys...), make(chan struct{}))
+
+			ack.checkOncePerTag(t, len(tt.keys))
+			for _, call := range ack.get() {
+				if call.method != "ack" {
+					t.Fatalf("delivery %d was not processed in parallel: %+v", call.tag, call)
+				}
+			}
+		})
+	}
+}
+
+func TestDispatchSettlesEveryDelivery(t *testing.T) {
+	s := newTestSubscriber(t, interfaces.SubscriberOptions{Concurrency: 3, OrderingKey: headerKey}, func(delivery *interfaces.Delivery) (interfaces.AckOutcome, error) {
+		if delivery.DeliveryTag%2 == 0 {
+			return interfaces.AckOutcomeDefault, fmt.Errorf("failed %d", delivery.DeliveryTag)
+		}
+		return interfaces.AckOutcomeDefault, nil
+	})
+
+	keys := []string{"a", "", "b", "a", "", "c", "b", "", "a", "c"}
+	ack := &fakeAcknowledger{}
+	s.dispatchLoop(newDeliveries(ack, keys...), make(chan struct{}))
+
+	ack.checkOncePerTag(t, len(keys))
+	for _, call := range ack.get() {
+		want := "ack"
+		if call.tag%2 == 0 {
+			want = "nack"
+		}
+		if call.method != want || call.multiple || call.requeue != (want == "nack") {
+			t.Fatalf("delivery %d settled with %+v, want %s", call.tag, call, want)
+		}
+	}
+}
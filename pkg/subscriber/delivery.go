@@ -0,0 +1,127 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"fmt"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Adapts a RabbitMessageHandler so it can be driven as a RabbitDeliveryHandler
+*/
+type messageHandlerAdapter struct {
+	handler *interfaces.RabbitMessageHandler
+}
+
+func (a messageHandlerAdapter) ProcessDelivery(delivery *interfaces.Delivery) error {
+	return (*a.handler).ProcessMessage(delivery.Body)
+}
+
+func NewMessageHandlerAdapter(handler *interfaces.RabbitMessageHandler) interfaces.RabbitDeliveryHandler {
+	return messageHandlerAdapter{
+		handler: handler,
+	}
+}
+
+/*
+	Adapts a RabbitDeliveryHandler so it can be driven as a RabbitAckHandler
+*/
+type deliveryHandlerAdapter struct {
+	handler interfaces.RabbitDeliveryHandler
+}
+
+func (a deliveryHandlerAdapter) HandleDelivery(delivery *interfaces.Delivery) (interfaces.AckOutcome, error) {
+	return interfaces.AckOutcomeDefault, a.handler.ProcessDelivery(delivery)
+}
+
+func NewDeliveryHandlerAdapter(handler interfaces.RabbitDeliveryHandler) interfaces.RabbitAckHandler {
+	return deliveryHandlerAdapter{
+		handler: handler,
+	}
+}
+
+/*
+	Everything but batches is dispatched through a RabbitAckHandler, wrap the
+	simpler handlers. Batch subscribers get a nil handler.
+*/
+func resolveHandler(options *interfaces.SubscriberOptions) (interfaces.RabbitAckHandler, error) {
+	count := 0
+	if options.Handler != nil {
+		count++
+	}
+	if options.DeliveryHandler != nil {
+		count++
+	}
+	if options.AckHandler != nil {
+		count++
+	}
+	if options.BatchHandler != nil {
+		count++
+	}
+
+	switch {
+	case count == 0:
+		return nil, ErrHandlerNotFound
+	case count > 1:
+		return nil, ErrMultipleHandlers
+	case options.BatchHandler != nil:
+		return nil, nil
+	case options.AckHandler != nil:
+		return *options.AckHandler, nil
+	case options.DeliveryHandler != nil:
+		return NewDeliveryHandlerAdapter(*options.DeliveryHandler), nil
+	default:
+		return NewDeliveryHandlerAdapter(NewMessageHandlerAdapter(options.Handler)), nil
+	}
+}
+
+func newDelivery(d *amqp.Delivery) *interfaces.Delivery {
+	return &interfaces.Delivery{
+		ConsumerTag: d.ConsumerTag,
+		DeliveryTag: d.DeliveryTag,
+		Redelivered: d.Redelivered,
+		Exchange:    d.Exchange,
+		RoutingKey:  d.RoutingKey,
+
+		Headers:         d.Headers,
+		ContentType:     d.ContentType,
+		ContentEncoding: d.ContentEncoding,
+		DeliveryMode:    d.DeliveryMode,
+		Priority:        d.Priority,
+		CorrelationId:   d.CorrelationId,
+		ReplyTo:         d.ReplyTo,
+		Expiration:      d.Expiration,
+		MessageId:       d.MessageId,
+		Timestamp:       d.Timestamp,
+		Type:            d.Type,
+		UserId:          d.UserId,
+		AppId:           d.AppId,
+
+		Body: d.Body,
+	}
+}
+
+/*
+	Orders deliveries by their routing key
+*/
+func OrderByRoutingKey(delivery *interfaces.Delivery) string {
+	return delivery.RoutingKey
+}
+
+/*
+	Orders deliveries by the string value of a header
+*/
+func OrderByHeader(name string) interfaces.OrderingKeyFunc {
+	return func(delivery *interfaces.Delivery) string {
+		value, ok := delivery.Headers[name]
+		if !ok || value == nil {
+			return ""
+		}
+		return fmt.Sprint(value)
+	}
+}
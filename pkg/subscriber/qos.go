@@ -0,0 +1,88 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	klog "k8s.io/klog/v2"
+)
+
+/*
+	Applies the prefetch to the channel. A zero count and size leaves the broker
+	default of unlimited unacked messages in place.
+*/
+func (s *Subscriber) applyQos() error {
+	s.qosMu.Lock()
+	defer s.qosMu.Unlock()
+
+	if s.prefetchCount == 0 && s.prefetchSize == 0 {
+		return nil
+	}
+
+	klog.V(3).Infof("Qos: %s (count: %d, size: %d)\n", s.GetName(), s.prefetchCount, s.prefetchSize)
+	return s.channel.Qos(s.prefetchCount, s.prefetchSize, false)
+}
+
+/*
+	Changes the prefetch at runtime. The new values also apply on every subsequent
+	Init and Retry.
+
+	A running stream consumer with DeferredAck is rejected: the restart resumes
+	from the committed offset, which sits below every unresolved token, so the
+	broker would deliver those messages a second time.
+*/
+func (s *Subscriber) SetPrefetch(count, size int) error {
+	klog.V(6).Infof("Subscriber.SetPrefetch ENTER\n")
+
+	if count < 0 || size < 0 || (s.stream && count == 0) {
+		klog.V(1).Infof("SetPrefetch %s invalid prefetch\n", s.GetName())
+		klog.V(6).Infof("Subscriber.SetPrefetch LEAVE\n")
+		return ErrInvalidPrefetch
+	}
+
+	options := *s.options.SubscriberOptions
+	options.PrefetchCount = count
+	err := validateBatch(&options)
+	if err != nil {
+		klog.V(1).Infof("validateBatch %s failed. Err: %v\n", s.GetName(), err)
+		klog.V(6).Infof("Subscriber.SetPrefetch LEAVE\n")
+		return err
+	}
+
+	s.consumeMu.Lock()
+	defer s.consumeMu.Unlock()
+
+	if s.running && s.stream && s.options.DeferredAck {
+		klog.V(1).Infof("SetPrefetch %s stream consumer with deferred acks\n", s.GetName())
+		klog.V(6).Infof("Subscriber.SetPrefetch LEAVE\n")
+		return ErrStreamPrefetchDeferredAck
+	}
+
+	s.qosMu.Lock()
+	s.prefetchCount = count
+	s.prefetchSize = size
+	s.qosMu.Unlock()
+
+	// the prefetch only applies to consumers started afterwards so restart ours
+	if s.running {
+		err := s.stopConsuming()
+		if err != nil {
+			klog.V(1).Infof("stopConsuming %s failed. Err: %v\n", s.GetName(), err)
+			klog.V(6).Infof("Subscriber.SetPrefetch LEAVE\n")
+			return err
+		}
+
+		err = s.startConsuming()
+		if err != nil {
+			klog.V(1).Infof("startConsuming %s failed. Err: %v\n", s.GetName(), err)
+			klog.V(6).Infof("Subscriber.SetPrefetch LEAVE\n")
+			s.running = false
+			return err
+		}
+	}
+
+	klog.V(4).Infof("Subscriber.SetPrefetch %s Succeeded\n", s.GetName())
+	klog.V(6).Infof("Subscriber.SetPrefetch LEAVE\n")
+
+	return nil
+}
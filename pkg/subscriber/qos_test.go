@@ -0,0 +1,87 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"testing"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+type batchHandler struct{}
+
+func (h batchHandler) ProcessBatch(deliveries []*interfaces.Delivery) ([]interfaces.AckOutcome, error) {
+	return nil, nil
+}
+
+func TestSetPrefetch(t *testing.T) {
+	var handler interfaces.RabbitBatchHandler = batchHandler{}
+
+	tests := []struct {
+		name     string
+		options  interfaces.SubscriberOptions
+		stream   bool
+		count    int
+		size     int
+		err      error
+		prefetch int
+	}{
+		{"plain consumer", interfaces.SubscriberOptions{}, false, 10, 0, nil, 10},
+		{"unlimited", interfaces.SubscriberOptions{PrefetchCount: 10}, false, 0, 0, nil, 0},
+		{"negative count", interfaces.SubscriberOptions{}, false, -1, 0, ErrInvalidPrefetch, 5},
+		{"negative size", interfaces.SubscriberOptions{}, false, 1, -1, ErrInvalidPrefetch, 5},
+		{"stream needs a count", interfaces.SubscriberOptions{}, true, 0, 0, ErrInvalidPrefetch, 5},
+		{"batch fits", interfaces.SubscriberOptions{BatchHandler: &handler, BatchSize: 50}, false, 50, 0, nil, 50},
+		{"batch unlimited", interfaces.SubscriberOptions{BatchHandler: &handler, BatchSize: 50}, false, 0, 0, nil, 0},
+		{"smaller than batch", interfaces.SubscriberOptions{BatchHandler: &handler, BatchSize: 50}, false, 49, 0, ErrBatchPrefetch, 5},
+		{"smaller than default batch", interfaces.SubscriberOptions{BatchHandler: &handler}, false, defaultBatchSize - 1, 0, ErrBatchPrefetch, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			s := &Subscriber{
+				options:       SubscriberOptions{SubscriberOptions: &options},
+				stream:        tt.stream,
+				prefetchCount: 5,
+			}
+
+			err := s.SetPrefetch(tt.count, tt.size)
+			if err != tt.err {
+				t.Fatalf("SetPrefetch() err = %v, want %v", err, tt.err)
+			}
+			if s.prefetchCount != tt.prefetch {
+				t.Fatalf("prefetch count = %d, want %d", s.prefetchCount, tt.prefetch)
+			}
+		})
+	}
+}
+
+func TestSetPrefetchStreamDeferredAck(t *testing.T) {
+	s := &Subscriber{
+		options:       SubscriberOptions{SubscriberOptions: &interfaces.SubscriberOptions{DeferredAck: true}},
+		stream:        true,
+		running:       true,
+		prefetchCount: 5,
+	}
+
+	// restarting would redeliver every message whose token is still unresolved
+	err := s.SetPrefetch(10, 0)
+	if err != ErrStreamPrefetchDeferredAck {
+		t.Fatalf("SetPrefetch() err = %v, want ErrStreamPrefetchDeferredAck", err)
+	}
+	if s.prefetchCount != 5 {
+		t.Fatalf("prefetch count = %d, want 5", s.prefetchCount)
+	}
+
+	// a stopped consumer starts from the committed offset anyway
+	s.running = false
+	err = s.SetPrefetch(10, 0)
+	if err != nil {
+		t.Fatalf("SetPrefetch failed. Err: %v", err)
+	}
+	if s.prefetchCount != 10 {
+		t.Fatalf("prefetch count = %d, want 10", s.prefetchCount)
+	}
+}
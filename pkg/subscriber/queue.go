@@ -0,0 +1,201 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"fmt"
+	"strings"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Builds the queue declare arguments. Arbitrary QueueArguments are applied first
+	and the typed options take precedence over them.
+*/
+func queueArguments(options *interfaces.SubscriberOptions) amqp.Table {
+	args := make(amqp.Table)
+	for key, value := range options.QueueArguments {
+		args[key] = value
+	}
+
+	if options.QueueType != interfaces.QueueTypeClassic {
+		args[common.ArgQueueType] = common.QueueTypeToString(options.QueueType)
+	}
+	if options.MaxLength > 0 {
+		args[common.ArgMaxLength] = options.MaxLength
+	}
+	if options.MaxLengthBytes > 0 {
+		args[common.ArgMaxLengthBytes] = options.MaxLengthBytes
+	}
+	if options.Overflow != interfaces.OverflowTypeDefault {
+		args[common.ArgOverflow] = common.OverflowTypeToString(options.Overflow)
+	}
+	if options.MessageTTL > 0 {
+		args[common.ArgMessageTTL] = options.MessageTTL.Milliseconds()
+	}
+	if options.QueueExpires > 0 {
+		args[common.ArgExpires] = options.QueueExpires.Milliseconds()
+	}
+	if options.DeliveryLimit > 0 {
+		args[common.ArgDeliveryLimit] = options.DeliveryLimit
+	}
+	if options.LazyMode {
+		args[common.ArgQueueMode] = common.QueueModeLazy
+	}
+
+	if len(args) == 0 {
+		return nil
+	}
+	return args
+}
+
+/*
+	Returns the effective queue type from the declare arguments
+*/
+func queueTypeFromArguments(args amqp.Table) string {
+	if queueType, ok := args[common.ArgQueueType].(string); ok {
+		return queueType
+	}
+	return common.QueueClassic
+}
+
+/*
+	Fails early on option combinations the broker would reject at declare time
+*/
+func validateQueueOptions(options *interfaces.SubscriberOptions, args amqp.Table) error {
+	if options.MaxLength < 0 || options.MaxLengthBytes < 0 || options.MessageTTL < 0 ||
+		options.QueueExpires < 0 || options.DeliveryLimit < 0 {
+		return ErrInvalidQueueArgument
+	}
+	if len(options.QueueName) > maxQueueNameLength || strings.HasPrefix(options.QueueName, reservedQueuePrefix) {
+		return ErrInvalidQueueName
+	}
+	if err := args.Validate(); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidQueueArgument, err)
+	}
+
+	stream := queueTypeFromArguments(args) == common.QueueStream
+	if !stream && (options.StreamOffset != interfaces.StreamOffsetTypeNext || options.OffsetStore != nil) {
+		return ErrStreamOffsetQueueType
+	}
+	if options.StreamOffset == interfaces.StreamOffsetTypeNumeric && options.StreamOffsetValue < 0 {
+		return ErrInvalidStreamOffset
+	}
+	if options.StreamOffset == interfaces.StreamOffsetTypeTimestamp && options.StreamOffsetTimestamp.IsZero() {
+		return ErrInvalidStreamOffset
+	}
+
+	var unsupported []string
+
+	switch queueTypeFromArguments(args) {
+	case common.QueueClassic:
+		unsupported = []string{common.ArgDeliveryLimit}
+	case common.QueueQuorum:
+		if options.Exclusive {
+			return ErrExclusiveQueueType
+		}
+		if !options.Durable {
+			return ErrNonDurableQueueType
+		}
+		if options.AutoDeleted {
+			return ErrAutoDeleteQueueType
+		}
+		if overflow, ok := args[common.ArgOverflow].(string); ok && overflow == common.OverflowRejectPublishDLX {
+			return fmt.Errorf("%w: %s=%s", ErrUnsupportedQueueArgument, common.ArgOverflow, overflow)
+		}
+		unsupported = []string{common.ArgQueueMode}
+	case common.QueueStream:
+		if options.Exclusive {
+			return ErrExclusiveQueueType
+		}
+		if !options.Durable {
+			return ErrNonDurableQueueType
+		}
+		if options.AutoDeleted {
+			return ErrAutoDeleteQueueType
+		}
+		if options.NoAck {
+			return ErrStreamRequiresAck
+		}
+		unsupported = []string{
+			common.ArgMaxLength,
+			common.ArgOverflow,
+			common.ArgMessageTTL,
+			common.ArgExpires,
+			common.ArgDeliveryLimit,
+			common.ArgQueueMode,
+		}
+	default:
+		return ErrInvalidQueueType
+	}
+
+	for _, key := range unsupported {
+		if _, ok := args[key]; ok {
+			return fmt.Errorf("%w: %s", ErrUnsupportedQueueArgument, key)
+		}
+	}
+
+	return nil
+}
+
+/*
+	Releases this instance's hold on a named queue once its consumer is stopped.
+	Durable named queues and streams are kept so that work survives restarts. A
+	transient named queue is only deleted once no other consumer is attached to
+	it. Returns true when the queue was kept.
+*/
+func (s *Subscriber) releaseNamedQueue() (bool, error) {
+	if s.queue == nil {
+		return true, nil
+	}
+	defer func() {
+		s.queue = nil
+	}()
+
+	if s.stream || s.options.Durable {
+		klog.V(3).Infof("Keeping named queue %s\n", s.queueName)
+		return true, nil
+	}
+
+	q, err := s.channel.QueueDeclarePassive(
+		s.queueName,           // name
+		s.options.Durable,     // durable
+		s.options.AutoDeleted, // auto-deleted
+		s.options.Exclusive,   // exclusive
+		false,                 // no-wait
+		s.queueArgs,           // arguments
+	)
+	if err != nil {
+		klog.V(1).Infof("QueueDeclarePassive %s failed. Err: %v\n", s.queueName, err)
+		return true, err
+	}
+	if q.Consumers > 0 {
+		klog.V(3).Infof("Named queue %s still has %d consumers\n", s.queueName, q.Consumers)
+		return true, nil
+	}
+
+	// if-unused guards against a consumer attaching since the check above
+	_, err = s.channel.QueueDelete(s.queueName, true, s.options.IfEmpty, s.options.NoWait)
+	if err != nil {
+		publishError, ok := err.(*amqp.Error)
+		if !ok {
+			return true, common.ErrUnresolvedRabbitError
+		}
+		if publishError.Code != 504 && publishError.Code != 406 {
+			klog.V(1).Infof("QueueDelete %s failed. Err: %v\n", s.queueName, err)
+			return true, err
+		} else if s.options.DeleteWarnings {
+			klog.V(1).Infof("QueueDelete %s failed. Err: %v\n", s.queueName, err)
+			return true, err
+		}
+		return true, nil
+	}
+
+	return false, nil
+}
@@ -0,0 +1,106 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+func TestQueueArguments(t *testing.T) {
+	tests := []struct {
+		name    string
+		options interfaces.SubscriberOptions
+		want    amqp.Table
+	}{
+		{"classic without arguments", interfaces.SubscriberOptions{}, nil},
+		{"quorum", interfaces.SubscriberOptions{QueueType: interfaces.QueueTypeQuorum, DeliveryLimit: 5}, amqp.Table{common.ArgQueueType: common.QueueQuorum, common.ArgDeliveryLimit: int64(5)}},
+		{"stream", interfaces.SubscriberOptions{QueueType: interfaces.QueueTypeStream, MaxLengthBytes: 1024}, amqp.Table{common.ArgQueueType: common.QueueStream, common.ArgMaxLengthBytes: int64(1024)}},
+		{"durations in milliseconds", interfaces.SubscriberOptions{MessageTTL: time.Second, QueueExpires: time.Minute}, amqp.Table{common.ArgMessageTTL: int64(1000), common.ArgExpires: int64(60000)}},
+		{"limits", interfaces.SubscriberOptions{MaxLength: 10, Overflow: interfaces.OverflowTypeRejectPublish, LazyMode: true}, amqp.Table{common.ArgMaxLength: int64(10), common.ArgOverflow: "reject-publish", common.ArgQueueMode: common.QueueModeLazy}},
+		{"typed options win", interfaces.SubscriberOptions{QueueArguments: amqp.Table{common.ArgMaxLength: int64(1), "x-custom": "kept"}, MaxLength: 2}, amqp.Table{common.ArgMaxLength: int64(2), "x-custom": "kept"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			args := queueArguments(&options)
+			if len(args) != len(tt.want) {
+				t.Fatalf("queueArguments() = %v, want %v", args, tt.want)
+			}
+			for key, value := range tt.want {
+				if args[key] != value {
+					t.Fatalf("queueArguments() = %v, want %v", args, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateQueueOptions(t *testing.T) {
+	quorum := interfaces.SubscriberOptions{QueueType: interfaces.QueueTypeQuorum, Durable: true}
+	stream := interfaces.SubscriberOptions{QueueType: interfaces.QueueTypeStream, Durable: true}
+
+	with := func(options interfaces.SubscriberOptions, change func(*interfaces.SubscriberOptions)) interfaces.SubscriberOptions {
+		change(&options)
+		return options
+	}
+
+	tests := []struct {
+		name    string
+		options interfaces.SubscriberOptions
+		err     error
+	}{
+		{"classic", interfaces.SubscriberOptions{}, nil},
+		{"quorum", quorum, nil},
+		{"stream", stream, nil},
+		{"negative max length", interfaces.SubscriberOptions{MaxLength: -1}, ErrInvalidQueueArgument},
+		{"negative ttl", interfaces.SubscriberOptions{MessageTTL: -time.Second}, ErrInvalidQueueArgument},
+		{"reserved name", interfaces.SubscriberOptions{QueueName: "amq.test"}, ErrInvalidQueueName},
+		{"long name", interfaces.SubscriberOptions{QueueName: strings.Repeat("q", maxQueueNameLength+1)}, ErrInvalidQueueName},
+		{"unencodable argument", interfaces.SubscriberOptions{QueueArguments: amqp.Table{"x-custom": struct{}{}}}, ErrInvalidQueueArgument},
+
+		{"exclusive quorum", with(quorum, func(o *interfaces.SubscriberOptions) { o.Exclusive = true }), ErrExclusiveQueueType},
+		{"transient quorum", with(quorum, func(o *interfaces.SubscriberOptions) { o.Durable = false }), ErrNonDurableQueueType},
+		{"auto deleted quorum", with(quorum, func(o *interfaces.SubscriberOptions) { o.AutoDeleted = true }), ErrAutoDeleteQueueType},
+		{"quorum with reject-publish-dlx", with(quorum, func(o *interfaces.SubscriberOptions) { o.Overflow = interfaces.OverflowTypeRejectPublishDLX }), ErrUnsupportedQueueArgument},
+		{"quorum in lazy mode", with(quorum, func(o *interfaces.SubscriberOptions) { o.LazyMode = true }), ErrUnsupportedQueueArgument},
+		{"quorum delivery limit", with(quorum, func(o *interfaces.SubscriberOptions) { o.DeliveryLimit = 3 }), nil},
+
+		{"exclusive stream", with(stream, func(o *interfaces.SubscriberOptions) { o.Exclusive = true }), ErrExclusiveQueueType},
+		{"transient stream", with(stream, func(o *interfaces.SubscriberOptions) { o.Durable = false }), ErrNonDurableQueueType},
+		{"stream with NoAck", with(stream, func(o *interfaces.SubscriberOptions) { o.NoAck = true }), ErrStreamRequiresAck},
+		{"stream with max length", with(stream, func(o *interfaces.SubscriberOptions) { o.MaxLength = 10 }), ErrUnsupportedQueueArgument},
+		{"stream with ttl", with(stream, func(o *interfaces.SubscriberOptions) { o.MessageTTL = time.Second }), ErrUnsupportedQueueArgument},
+		{"stream with raw delivery limit", with(stream, func(o *interfaces.SubscriberOptions) { o.QueueArguments = amqp.Table{common.ArgDeliveryLimit: int64(1)} }), ErrUnsupportedQueueArgument},
+		{"stream max bytes", with(stream, func(o *interfaces.SubscriberOptions) { o.MaxLengthBytes = 1024 }), nil},
+
+		{"classic delivery limit", interfaces.SubscriberOptions{DeliveryLimit: 3}, ErrUnsupportedQueueArgument},
+		{"unknown type through arguments", interfaces.SubscriberOptions{QueueArguments: amqp.Table{common.ArgQueueType: "unknown"}}, ErrInvalidQueueType},
+
+		{"stream offset on a classic queue", interfaces.SubscriberOptions{StreamOffset: interfaces.StreamOffsetTypeFirst}, ErrStreamOffsetQueueType},
+		{"negative numeric offset", with(stream, func(o *interfaces.SubscriberOptions) {
+			o.StreamOffset = interfaces.StreamOffsetTypeNumeric
+			o.StreamOffsetValue = -1
+		}), ErrInvalidStreamOffset},
+		{"timestamp offset without a timestamp", with(stream, func(o *interfaces.SubscriberOptions) { o.StreamOffset = interfaces.StreamOffsetTypeTimestamp }), ErrInvalidStreamOffset},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tt.options
+			err := validateQueueOptions(&options, queueArguments(&options))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("validateQueueOptions() err = %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
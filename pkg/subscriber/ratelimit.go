@@ -0,0 +1,49 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"sync/atomic"
+
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Holds the delivery back until it fits within the rate limit. Unacked
+	deliveries count towards the prefetch, so the broker stops sending once the
+	subscriber falls behind. Stopping the consumer cuts the wait short.
+*/
+func (s *Subscriber) throttle(size int) {
+	if !s.limiter.Limited() || s.limiter.Allow(size) {
+		return
+	}
+
+	waited, err := s.limiter.Wait(s.throttleCtx, size)
+	atomic.AddUint64(&s.stats.throttled, 1)
+	atomic.AddInt64(&s.stats.throttleWait, int64(waited))
+	if err != nil {
+		klog.V(3).Infof("Throttle %s interrupted. Err: %v\n", s.GetName(), err)
+	}
+}
+
+/*
+	Changes the rate limit at runtime
+*/
+func (s *Subscriber) SetRateLimit(limit interfaces.RateLimit) error {
+	klog.V(6).Infof("Subscriber.SetRateLimit ENTER\n")
+
+	err := s.limiter.SetLimit(limit)
+	if err != nil {
+		klog.V(1).Infof("SetRateLimit %s failed. Err: %v\n", s.GetName(), err)
+		klog.V(6).Infof("Subscriber.SetRateLimit LEAVE\n")
+		return err
+	}
+
+	klog.V(4).Infof("Subscriber.SetRateLimit %s Succeeded\n", s.GetName())
+	klog.V(6).Infof("Subscriber.SetRateLimit LEAVE\n")
+
+	return nil
+}
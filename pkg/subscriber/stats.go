@@ -0,0 +1,93 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"sync/atomic"
+	"time"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+type stats struct {
+	received uint64
+	acked    uint64
+	nacked   uint64
+	rejected uint64
+
+	throttled    uint64
+	throttleWait int64
+
+	decompressed          uint64
+	decompressionFailures uint64
+	compressedBytes       uint64
+	decompressedBytes     uint64
+	decompressionTime     int64
+
+	decrypted          uint64
+	decryptionFailures uint64
+
+	verified             uint64
+	verificationFailures uint64
+
+	claimChecked       uint64
+	claimCheckFailures uint64
+}
+
+func (s *stats) countOutcome(outcome interfaces.AckOutcome) {
+	switch outcome {
+	case interfaces.AckOutcomeNackRequeue, interfaces.AckOutcomeNackDiscard:
+		atomic.AddUint64(&s.nacked, 1)
+	case interfaces.AckOutcomeReject:
+		atomic.AddUint64(&s.rejected, 1)
+	default:
+		atomic.AddUint64(&s.acked, 1)
+	}
+}
+
+func (s *Subscriber) GetStats() interfaces.SubscriberStats {
+	s.ackMu.Lock()
+	outstanding := uint64(len(s.outstanding))
+	s.ackMu.Unlock()
+
+	circuitState := interfaces.CircuitStateClosed
+	if s.breaker != nil {
+		circuitState = s.breaker.State()
+	}
+
+	compressedBytes := atomic.LoadUint64(&s.stats.compressedBytes)
+	decompressedBytes := atomic.LoadUint64(&s.stats.decompressedBytes)
+
+	var ratio float64
+	if compressedBytes > 0 {
+		ratio = float64(decompressedBytes) / float64(compressedBytes)
+	}
+
+	return interfaces.SubscriberStats{
+		Received:        atomic.LoadUint64(&s.stats.received),
+		Acked:           atomic.LoadUint64(&s.stats.acked),
+		Nacked:          atomic.LoadUint64(&s.stats.nacked),
+		Rejected:        atomic.LoadUint64(&s.stats.rejected),
+		OutstandingAcks: outstanding,
+		Throttled:       atomic.LoadUint64(&s.stats.throttled),
+		ThrottleWait:    time.Duration(atomic.LoadInt64(&s.stats.throttleWait)),
+		CircuitState:    circuitState,
+
+		Decompressed:          atomic.LoadUint64(&s.stats.decompressed),
+		DecompressionFailures: atomic.LoadUint64(&s.stats.decompressionFailures),
+		CompressedBytes:       compressedBytes,
+		DecompressedBytes:     decompressedBytes,
+		CompressionRatio:      ratio,
+		DecompressionTime:     time.Duration(atomic.LoadInt64(&s.stats.decompressionTime)),
+
+		Decrypted:          atomic.LoadUint64(&s.stats.decrypted),
+		DecryptionFailures: atomic.LoadUint64(&s.stats.decryptionFailures),
+
+		Verified:             atomic.LoadUint64(&s.stats.verified),
+		VerificationFailures: atomic.LoadUint64(&s.stats.verificationFailures),
+
+		ClaimChecked:       atomic.LoadUint64(&s.stats.claimChecked),
+		ClaimCheckFailures: atomic.LoadUint64(&s.stats.claimCheckFailures),
+	}
+}
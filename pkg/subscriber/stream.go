@@ -0,0 +1,180 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"errors"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	offset "github.com/dvonthenen/rabbitmq-manager/pkg/offset"
+)
+
+/*
+	Builds the consume arguments for a stream queue. A previously stored offset
+	always wins over the configured starting offset so that a restarted consumer
+	resumes right after the last message it processed. A corrupt stored offset is
+	ignored in favor of the configured one rather than stopping the consumer.
+*/
+func (s *Subscriber) streamArguments() (amqp.Table, error) {
+	stored, found, err := (*s.offsetStore).LoadOffset(s.offsetName)
+	if errors.Is(err, offset.ErrCorruptOffset) {
+		klog.V(1).Infof("Stored offset %s is corrupt, starting from the configured offset\n", s.offsetName)
+		found, err = false, nil
+	}
+	if err != nil {
+		klog.V(1).Infof("LoadOffset %s failed. Err: %v\n", s.offsetName, err)
+		return nil, err
+	}
+	if found {
+		klog.V(3).Infof("Resuming %s from offset %d\n", s.offsetName, stored+1)
+		return amqp.Table{common.ArgStreamOffset: stored + 1}, nil
+	}
+
+	switch s.options.StreamOffset {
+	case interfaces.StreamOffsetTypeNumeric:
+		return amqp.Table{common.ArgStreamOffset: s.options.StreamOffsetValue}, nil
+	case interfaces.StreamOffsetTypeTimestamp:
+		return amqp.Table{common.ArgStreamOffset: s.options.StreamOffsetTimestamp}, nil
+	default:
+		return amqp.Table{common.ArgStreamOffset: common.StreamOffsetTypeToString(s.options.StreamOffset)}, nil
+	}
+}
+
+/*
+	Marks a stream offset as in flight. Called in delivery order.
+*/
+func (s *Subscriber) beginOffset(d *amqp.Delivery) {
+	offset, ok := d.Headers[common.ArgStreamOffset].(int64)
+	if !ok {
+		return
+	}
+
+	s.offsetMu.Lock()
+	s.inflightOffsets[offset] = struct{}{}
+	s.offsetMu.Unlock()
+}
+
+/*
+	Forgets the offsets that were in flight on a closed channel, they are
+	delivered again from the committed offset
+*/
+func (s *Subscriber) resetOffsets() {
+	s.offsetMu.Lock()
+	s.inflightOffsets = make(map[int64]struct{})
+	s.offsetMu.Unlock()
+}
+
+/*
+	A stream keeps every message, so it can neither requeue nor dead letter. A
+	requeued message stays in flight, which holds the committed offset below it
+	until the channel is recovered or the subscriber restarts and reads it
+	again. Discarded and rejected messages are skipped like acked ones.
+*/
+func (s *Subscriber) settleOffset(d *amqp.Delivery, outcome interfaces.AckOutcome) {
+	switch outcome {
+	case interfaces.AckOutcomeNackRequeue:
+		klog.V(1).Infof("Stream %s cannot requeue %d, holding the offset until it is read again\n", s.GetName(), d.DeliveryTag)
+		return
+	case interfaces.AckOutcomeNackDiscard, interfaces.AckOutcomeReject:
+		klog.V(3).Infof("Stream %s cannot dead letter %d, skipping it\n", s.GetName(), d.DeliveryTag)
+	}
+
+	s.trackOffset(d)
+}
+
+/*
+	Records the offset of a processed stream message. Deliveries can complete out
+	of order (worker pool, deferred acks) so only the offset below the oldest one
+	still in flight is committed, a restart never skips an unprocessed message.
+	The store is written every OffsetCommitCount messages or OffsetCommitInterval,
+	whichever comes first, and when consuming stops.
+*/
+func (s *Subscriber) trackOffset(d *amqp.Delivery) {
+	offset, ok := d.Headers[common.ArgStreamOffset].(int64)
+	if !ok {
+		klog.V(1).Infof("Delivery on %s has no stream offset\n", s.GetName())
+		return
+	}
+
+	s.offsetMu.Lock()
+
+	delete(s.inflightOffsets, offset)
+	if !s.hasCompleted || offset > s.maxCompleted {
+		s.maxCompleted = offset
+		s.hasCompleted = true
+	}
+
+	committed := s.maxCompleted
+	for inflight := range s.inflightOffsets {
+		if inflight-1 < committed {
+			committed = inflight - 1
+		}
+	}
+	if s.hasOffset && committed <= s.lastOffset {
+		s.offsetMu.Unlock()
+		return
+	}
+
+	s.lastOffset = committed
+	s.hasOffset = true
+	s.uncommitted++
+
+	due := s.uncommitted >= s.offsetCommitCount
+	if !due && s.commitTimer == nil {
+		s.commitTimer = time.AfterFunc(s.offsetCommitInterval, s.flushOffset)
+	}
+	s.offsetMu.Unlock()
+
+	if due {
+		s.flushOffset()
+	}
+}
+
+/*
+	Stores the last processed offset unless it is stored already. The store is
+	written outside offsetMu so deliveries keep completing, commitMu keeps an
+	older offset from overwriting a newer one. A failed store is retried with the
+	next commit.
+*/
+func (s *Subscriber) flushOffset() {
+	s.commitMu.Lock()
+	defer s.commitMu.Unlock()
+
+	s.offsetMu.Lock()
+	if s.commitTimer != nil {
+		s.commitTimer.Stop()
+		s.commitTimer = nil
+	}
+	if s.uncommitted == 0 {
+		s.offsetMu.Unlock()
+		return
+	}
+	committed := s.lastOffset
+	s.uncommitted = 0
+	s.offsetMu.Unlock()
+
+	err := (*s.offsetStore).StoreOffset(s.offsetName, committed)
+	if err != nil {
+		klog.V(1).Infof("StoreOffset %s failed. Err: %v\n", s.offsetName, err)
+
+		s.offsetMu.Lock()
+		s.uncommitted++
+		s.offsetMu.Unlock()
+	}
+}
+
+/*
+	Returns the offset of the last processed stream message
+*/
+func (s *Subscriber) GetLastOffset() (int64, bool) {
+	s.offsetMu.Lock()
+	defer s.offsetMu.Unlock()
+
+	return s.lastOffset, s.hasOffset
+}
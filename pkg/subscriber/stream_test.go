@@ -0,0 +1,188 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	offset "github.com/dvonthenen/rabbitmq-manager/pkg/offset"
+)
+
+type offsetStore struct {
+	offset int64
+	found  bool
+	err    error
+}
+
+func (o *offsetStore) LoadOffset(name string) (int64, bool, error) {
+	return o.offset, o.found, o.err
+}
+
+func (o *offsetStore) StoreOffset(name string, offset int64) error {
+	return nil
+}
+
+func TestStreamArguments(t *testing.T) {
+	errUnavailable := errors.New("store unavailable")
+
+	tests := []struct {
+		name    string
+		store   offsetStore
+		options interfaces.SubscriberOptions
+		want    interface{}
+		err     error
+	}{
+		{"stored offset wins", offsetStore{offset: 41, found: true}, interfaces.SubscriberOptions{StreamOffset: interfaces.StreamOffsetTypeFirst}, int64(42), nil},
+		{"configured start", offsetStore{}, interfaces.SubscriberOptions{StreamOffset: interfaces.StreamOffsetTypeFirst}, common.StreamOffsetFirst, nil},
+		{"configured numeric", offsetStore{}, interfaces.SubscriberOptions{StreamOffset: interfaces.StreamOffsetTypeNumeric, StreamOffsetValue: 7}, int64(7), nil},
+		{"corrupt falls back", offsetStore{err: offset.ErrCorruptOffset}, interfaces.SubscriberOptions{StreamOffset: interfaces.StreamOffsetTypeLast}, common.StreamOffsetLast, nil},
+		{"store failure", offsetStore{err: errUnavailable}, interfaces.SubscriberOptions{}, nil, errUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var store interfaces.OffsetStore = &tt.store
+			options := tt.options
+			s := &Subscriber{
+				options:     SubscriberOptions{SubscriberOptions: &options},
+				offsetName:  "test",
+				offsetStore: &store,
+			}
+
+			args, err := s.streamArguments()
+			if err != tt.err {
+				t.Fatalf("streamArguments() err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if args[common.ArgStreamOffset] != tt.want {
+				t.Fatalf("stream offset = %v, want %v", args[common.ArgStreamOffset], tt.want)
+			}
+			if len(args) != 1 {
+				t.Fatalf("unexpected arguments %v", args)
+			}
+		})
+	}
+}
+
+
+type recordingStore struct {
+	stored []int64
+	mu     sync.Mutex
+}
+
+func (r *recordingStore) LoadOffset(name string) (int64, bool, error) {
+	return 0, false, nil
+}
+
+func (r *recordingStore) StoreOffset(name string, offset int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.stored = append(r.stored, offset)
+	return nil
+}
+
+func (r *recordingStore) get() []int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return append([]int64{}, r.stored...)
+}
+
+func newStreamSubscriber(store *recordingStore, count int, interval time.Duration) *Subscriber {
+	var offsetStore interfaces.OffsetStore = store
+	return &Subscriber{
+		options:              SubscriberOptions{SubscriberOptions: &interfaces.SubscriberOptions{Name: "test"}},
+		stream:               true,
+		offsetName:           "test",
+		offsetStore:          &offsetStore,
+		offsetCommitCount:    count,
+		offsetCommitInterval: interval,
+		inflightOffsets:      make(map[int64]struct{}),
+	}
+}
+
+func streamDelivery(offset int64) *amqp.Delivery {
+	return &amqp.Delivery{Headers: amqp.Table{common.ArgStreamOffset: offset}}
+}
+
+/*
+	Steps are offsets, a negative one begins offset -n-1 instead of completing it
+	and "flush" stops consuming
+*/
+func TestOffsetCommits(t *testing.T) {
+	tests := []struct {
+		name   string
+		count  int
+		steps  []interface{}
+		stored []int64
+		last   int64
+	}{
+		{"every count messages", 3, []interface{}{int64(0), int64(1), int64(2), int64(3), int64(4)}, []int64{2}, 4},
+		{"flushed on stop", 3, []interface{}{int64(0), int64(1), int64(2), int64(3), int64(4), "flush"}, []int64{2, 4}, 4},
+		{"nothing new to flush", 2, []interface{}{int64(0), int64(1), "flush"}, []int64{1}, 1},
+		{"held below an in flight offset", 1, []interface{}{int64(-1), int64(-2), int64(1), int64(0)}, []int64{-1, 1}, 1},
+		{"not below the stored offset", 1, []interface{}{int64(5), int64(3)}, []int64{5}, 5},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &recordingStore{}
+			s := newStreamSubscriber(store, tt.count, time.Hour)
+
+			for _, step := range tt.steps {
+				switch step := step.(type) {
+				case string:
+					s.flushOffset()
+				case int64:
+					if step < 0 {
+						s.beginOffset(streamDelivery(-step - 1))
+					} else {
+						s.trackOffset(streamDelivery(step))
+					}
+				}
+			}
+
+			got := store.get()
+			if len(got) != len(tt.stored) {
+				t.Fatalf("stored = %v, want %v", got, tt.stored)
+			}
+			for i := range got {
+				if got[i] != tt.stored[i] {
+					t.Fatalf("stored = %v, want %v", got, tt.stored)
+				}
+			}
+			if last, _ := s.GetLastOffset(); last != tt.last {
+				t.Fatalf("GetLastOffset() = %d, want %d", last, tt.last)
+			}
+		})
+	}
+}
+
+func TestOffsetCommitInterval(t *testing.T) {
+	store := &recordingStore{}
+	s := newStreamSubscriber(store, 100, 20*time.Millisecond)
+
+	s.trackOffset(streamDelivery(7))
+
+	deadline := time.Now().Add(time.Second)
+	for len(store.get()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("offset never stored")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := store.get(); got[0] != 7 {
+		t.Fatalf("stored = %v, want [7]", got)
+	}
+}
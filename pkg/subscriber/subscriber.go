@@ -7,17 +7,180 @@ import (
 	amqp "github.com/rabbitmq/amqp091-go"
 	klog "k8s.io/klog/v2"
 
+	circuitbreaker "github.com/dvonthenen/rabbitmq-manager/pkg/circuitbreaker"
 	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	offset "github.com/dvonthenen/rabbitmq-manager/pkg/offset"
+	ratelimit "github.com/dvonthenen/rabbitmq-manager/pkg/ratelimit"
+	signing "github.com/dvonthenen/rabbitmq-manager/pkg/signing"
 )
 
-func New(options SubscriberOptions) *Subscriber {
+func New(options SubscriberOptions) (*Subscriber, error) {
+	handler, err := resolveHandler(options.SubscriberOptions)
+	if err != nil {
+		klog.V(1).Infof("resolveHandler %s failed. Err: %v\n", options.Name, err)
+		return nil, err
+	}
+
+	queueArgs := queueArguments(options.SubscriberOptions)
+
+	err = validateQueueOptions(options.SubscriberOptions, queueArgs)
+	if err != nil {
+		klog.V(1).Infof("validateQueueOptions %s failed. Err: %v\n", options.Name, err)
+		return nil, err
+	}
+
+	if options.PrefetchCount < 0 || options.PrefetchSize < 0 {
+		klog.V(1).Infof("Subscriber %s has a negative prefetch\n", options.Name)
+		return nil, ErrInvalidPrefetch
+	}
+	if options.OffsetCommitCount < 0 || options.OffsetCommitInterval < 0 {
+		klog.V(1).Infof("Subscriber %s has a negative offset commit\n", options.Name)
+		return nil, ErrInvalidOffsetCommit
+	}
+	if options.Concurrency < 0 {
+		klog.V(1).Infof("Subscriber %s has a negative concurrency\n", options.Name)
+		return nil, ErrInvalidConcurrency
+	}
+	if options.DeferredAck && options.NoAck {
+		klog.V(1).Infof("Subscriber %s uses DeferredAck with NoAck\n", options.Name)
+		return nil, ErrDeferredAckNoAck
+	}
+
+	if options.MaxDecompressedSize < 0 {
+		klog.V(1).Infof("Subscriber %s has a negative MaxDecompressedSize\n", options.Name)
+		return nil, ErrInvalidMaxDecompressedSize
+	}
+	if options.RequireEncryption && options.KeyProvider == nil {
+		klog.V(1).Infof("Subscriber %s requires encryption without a KeyProvider\n", options.Name)
+		return nil, ErrRequireEncryptionKeyProvider
+	}
+	if options.ReplayWindow < 0 {
+		klog.V(1).Infof("Subscriber %s has a negative ReplayWindow\n", options.Name)
+		return nil, ErrInvalidReplayWindow
+	}
+	// an unsigned timestamp proves nothing
+	if options.ReplayWindow > 0 && options.Verifier == nil {
+		klog.V(1).Infof("Subscriber %s has a ReplayWindow without a Verifier\n", options.Name)
+		return nil, ErrReplayWindowVerifier
+	}
+	if options.ReplayCacheSize < 0 {
+		klog.V(1).Infof("Subscriber %s has a negative ReplayCacheSize\n", options.Name)
+		return nil, ErrInvalidReplayCacheSize
+	}
+	if len(options.RequiredSignedHeaders) > 0 && options.Verifier == nil {
+		klog.V(1).Infof("Subscriber %s has RequiredSignedHeaders without a Verifier\n", options.Name)
+		return nil, ErrRequiredSignedHeadersVerifier
+	}
+	err = signing.ValidateHeaderNames(options.RequiredSignedHeaders)
+	if err != nil {
+		klog.V(1).Infof("ValidateHeaderNames %s failed. Err: %v\n", options.Name, err)
+		return nil, err
+	}
+	if options.BreakerThreshold > 0 && options.NoAck {
+		klog.V(1).Infof("Subscriber %s uses a circuit breaker with NoAck\n", options.Name)
+		return nil, ErrBreakerNoAck
+	}
+
+	err = validateBatch(options.SubscriberOptions)
+	if err != nil {
+		klog.V(1).Infof("validateBatch %s failed. Err: %v\n", options.Name, err)
+		return nil, err
+	}
+
+	err = validateBindings(options.SubscriberOptions)
+	if err != nil {
+		klog.V(1).Infof("validateBindings %s failed. Err: %v\n", options.Name, err)
+		return nil, err
+	}
+
+	limiter, err := ratelimit.NewThrottle(options.RateLimit)
+	if err != nil {
+		klog.V(1).Infof("NewThrottle %s failed. Err: %v\n", options.Name, err)
+		return nil, err
+	}
+
 	rabbit := &Subscriber{
-		options: options,
-		channel: options.Channel,
-		handler: options.Handler,
-		running: false,
+		options:   options,
+		limiter:   limiter,
+		channel:   options.Channel,
+		handler:   handler,
+		queueName: options.QueueName,
+		queueArgs: queueArgs,
+		stream:    queueTypeFromArguments(queueArgs) == common.QueueStream,
+		running:   false,
+
+		prefetchCount:   options.PrefetchCount,
+		prefetchSize:    options.PrefetchSize,
+		outstanding:     make(map[*acknowledger]struct{}),
+		inflightOffsets: make(map[int64]struct{}),
+	}
+
+	if options.BatchHandler != nil {
+		rabbit.batchHandler = *options.BatchHandler
+
+		rabbit.batchSize = options.BatchSize
+		if rabbit.batchSize == 0 {
+			rabbit.batchSize = defaultBatchSize
+		}
+		rabbit.batchTimeout = options.BatchTimeout
+		if rabbit.batchTimeout == 0 {
+			rabbit.batchTimeout = defaultBatchTimeout
+		}
+	}
+
+	if options.BreakerThreshold > 0 {
+		rabbit.breaker, err = circuitbreaker.New(options.BreakerThreshold, options.BreakerOpenInterval, options.BreakerProbes, rabbit.circuitChanged)
+		if err != nil {
+			klog.V(1).Infof("circuitbreaker.New %s failed. Err: %v\n", options.Name, err)
+			return nil, err
+		}
+	}
+
+	if options.ReplayWindow > 0 {
+		rabbit.replays = signing.NewReplayCache(options.ReplayWindow, options.ReplayCacheSize)
+	}
+
+	// streams require a prefetch to grant consumer credit
+	if rabbit.stream && rabbit.prefetchCount == 0 {
+		rabbit.prefetchCount = defaultStreamPrefetch
+	}
+
+	if rabbit.stream {
+		// streams outlive the consumer so they need a stable name to resume from
+		if rabbit.queueName == "" {
+			rabbit.queueName = options.Name
+		}
+
+		rabbit.offsetName = options.OffsetName
+		if rabbit.offsetName == "" {
+			rabbit.offsetName = rabbit.queueName
+		}
+
+		rabbit.offsetCommitCount = options.OffsetCommitCount
+		if rabbit.offsetCommitCount == 0 {
+			rabbit.offsetCommitCount = defaultOffsetCommitCount
+		}
+		rabbit.offsetCommitInterval = options.OffsetCommitInterval
+		if rabbit.offsetCommitInterval == 0 {
+			rabbit.offsetCommitInterval = defaultOffsetCommitInterval
+		}
+
+		rabbit.offsetStore = options.OffsetStore
+		if rabbit.offsetStore == nil {
+			fileStore, err := offset.NewFileStore(offset.DefaultDirectory)
+			if err != nil {
+				klog.V(1).Infof("NewFileStore failed. Err: %v\n", err)
+				return nil, err
+			}
+
+			var offsetStore interfaces.OffsetStore
+			offsetStore = fileStore
+			rabbit.offsetStore = &offsetStore
+		}
 	}
-	return rabbit
+
+	return rabbit, nil
 }
 
 func (s *Subscriber) GetName() string {
@@ -27,11 +190,19 @@ func (s *Subscriber) GetName() string {
 func (s *Subscriber) Init() error {
 	klog.V(6).Infof("Subscriber.Init ENTER\n")
 
+	s.consumeMu.Lock()
+	defer s.consumeMu.Unlock()
+
 	if s.running {
 		klog.V(1).Infof("Subscribe already running\n")
 		klog.V(6).Infof("Subscriber.Init LEAVE\n")
 		return nil
 	}
+	if s.paused {
+		klog.V(1).Infof("Subscribe paused by the circuit breaker\n")
+		klog.V(6).Infof("Subscriber.Init LEAVE\n")
+		return nil
+	}
 
 	klog.V(3).Infof("ExchangeDeclare: %s\n", s.GetName())
 	err := s.channel.ExchangeDeclare(
@@ -51,12 +222,12 @@ func (s *Subscriber) Init() error {
 
 	klog.V(3).Infof("QueueDeclare: %s\n", s.GetName())
 	q, err := s.channel.QueueDeclare(
-		"",                    // name
+		s.queueName,           // name
 		s.options.Durable,     // durable
 		s.options.AutoDeleted, // auto-deleted
 		s.options.Exclusive,   // exclusive
 		s.options.NoWait,      // no-wait
-		nil,                   // arguments
+		s.queueArgs,           // arguments
 	)
 	if err != nil {
 		klog.V(1).Infof("QueueDeclare %s failed. Err: %v\n", s.GetName(), err)
@@ -65,56 +236,30 @@ func (s *Subscriber) Init() error {
 	}
 	s.queue = &q
 
-	klog.V(3).Infof("QueueBind: %s\n", s.GetName())
-	err = s.channel.QueueBind(
-		q.Name,         // queue name
-		"",             // routing key
-		s.options.Name, // exchange
-		false,
-		nil)
-	if err != nil {
-		klog.V(1).Infof("QueueBind %s failed. Err: %v\n", s.GetName(), err)
-		klog.V(6).Infof("Subscriber.Init LEAVE\n")
-		return err
+	s.bindings = make([]binding, 0)
+	for _, binding := range queueBindings(s.options.SubscriberOptions) {
+		klog.V(3).Infof("QueueBind: %s (key: %s)\n", s.GetName(), binding.key)
+		err = s.channel.QueueBind(
+			q.Name,         // queue name
+			binding.key,    // routing key
+			s.options.Name, // exchange
+			false,
+			binding.args)
+		if err != nil {
+			klog.V(1).Infof("QueueBind %s failed. Err: %v\n", s.GetName(), err)
+			klog.V(6).Infof("Subscriber.Init LEAVE\n")
+			return err
+		}
+		s.bindings = append(s.bindings, binding)
 	}
 
-	klog.V(3).Infof("Consume: %s\n", s.GetName())
-	msgs, err := s.channel.Consume(
-		s.queue.Name,        // queue
-		"",                  // consumer tag
-		s.options.NoLocal,   // no local
-		s.options.NoAck,     // no ack
-		s.options.Exclusive, // exclusive
-		s.options.NoWait,    // no wait
-		nil,                 // args
-	)
+	err = s.startConsuming()
 	if err != nil {
-		klog.V(1).Infof("Consume %s failed. Err: %v\n", s.GetName(), err)
+		klog.V(1).Infof("startConsuming %s failed. Err: %v\n", s.GetName(), err)
 		klog.V(6).Infof("Subscriber.Init LEAVE\n")
 		return err
 	}
-
-	klog.V(3).Infof("Subscriber.Init Running message loop...\n")
 	s.running = true
-	s.stopChan = make(chan struct{})
-	go func() {
-		for {
-			select {
-			default:
-				for d := range msgs {
-					klog.V(5).Infof(" [x] %s\n", d.Body)
-
-					err := (*s.handler).ProcessMessage(d.Body)
-					if err != nil {
-						klog.V(1).Infof("ProcessMessage() failed. Err: %v\n", err)
-					}
-				}
-			case <-s.stopChan:
-				klog.V(5).Infof("Exiting Subscriber Loop\n")
-				return
-			}
-		}
-	}()
 
 	klog.V(4).Infof("Subscriber.Init Succeeded\n")
 	klog.V(6).Infof("Subscriber.Init LEAVE\n")
@@ -150,24 +295,101 @@ func (s *Subscriber) Retry() error {
 	return retErr
 }
 
+/*
+	Moves the subscriber onto a new channel after its old one closed. The broker
+	requeued whatever was unacked so nothing is settled on the old channel.
+*/
+func (s *Subscriber) Recover(channel *amqp.Channel) error {
+	klog.V(6).Infof("Subscriber.Recover ENTER\n")
+	klog.V(3).Infof("Subscriber.Recover %s called\n", s.GetName())
+
+	// closing the old channel ends the message loop
+	s.consumeMu.Lock()
+	if s.stopThrottle != nil {
+		s.stopThrottle()
+	}
+	if s.channel != nil {
+		s.channel.Close()
+	}
+	if s.doneChan != nil {
+		<-s.doneChan
+		s.doneChan = nil
+	}
+	s.running = false
+	s.consumeMu.Unlock()
+
+	s.expireOutstanding()
+	if s.stream {
+		s.flushOffset()
+		s.resetOffsets()
+	}
+
+	s.channel = channel
+	s.queue = nil
+	s.bindings = nil
+
+	err := s.Init()
+	if err == nil {
+		klog.V(4).Infof("Subscriber.Recover Succeeded\n")
+	} else {
+		klog.V(1).Infof("Subscriber.Recover failed. Err: %v\n", err)
+	}
+	klog.V(6).Infof("Subscriber.Recover LEAVE\n")
+
+	return err
+}
+
+func (s *Subscriber) IsClosed() bool {
+	return s.channel == nil || s.channel.IsClosed()
+}
+
 func (s *Subscriber) teardownMinusChannel() error {
+	s.consumeMu.Lock()
+	if s.running {
+		err := s.stopConsuming()
+		if err != nil {
+			klog.V(1).Infof("stopConsuming %s failed. Err: %v\n", s.GetName(), err)
+		}
+	}
 	s.running = false
 
-	close(s.stopChan)
-	<-s.stopChan
+	// a fresh start also starts with a closed breaker
+	s.paused = false
+	if s.breaker != nil {
+		s.breaker.Reset()
+	}
+	s.consumeMu.Unlock()
+
+	// settle whatever the handler never resolved while the channel is still open
+	s.nackOutstanding()
 
 	var retErr error
 	retErr = nil
 
-	// clean up queue related stuff
-	if s.queue != nil {
-		err := s.channel.QueueUnbind(s.queue.Name, "", s.options.Name, nil)
+	// named queues can be shared with other instances so only release our consumer
+	if s.queueName != "" {
+		kept, err := s.releaseNamedQueue()
 		if err != nil {
-			klog.V(1).Infof("QueueUnbind %s failed. Err: %v\n", s.queue.Name, err)
+			klog.V(1).Infof("releaseNamedQueue %s failed. Err: %v\n", s.queueName, err)
 			retErr = err
 		}
+		if kept {
+			return retErr
+		}
+	}
 
-		_, err = s.channel.QueueDelete(s.queue.Name, s.options.IfUnused, s.options.IfEmpty, s.options.NoWait)
+	// clean up queue related stuff
+	if s.queue != nil {
+		for _, binding := range s.bindings {
+			err := s.channel.QueueUnbind(s.queue.Name, binding.key, s.options.Name, binding.args)
+			if err != nil {
+				klog.V(1).Infof("QueueUnbind %s (key: %s) failed. Err: %v\n", s.queue.Name, binding.key, err)
+				retErr = err
+			}
+		}
+		s.bindings = nil
+
+		_, err := s.channel.QueueDelete(s.queue.Name, s.options.IfUnused, s.options.IfEmpty, s.options.NoWait)
 		if err != nil {
 			publishError, ok := err.(*amqp.Error)
 			if ok {
@@ -218,6 +440,11 @@ func (s *Subscriber) Teardown() error {
 		retErr = err
 	}
 
+	// including the offsets of deliveries settled during teardown
+	if s.stream {
+		s.flushOffset()
+	}
+
 	if s.channel != nil {
 		s.channel.Close()
 		s.channel = nil
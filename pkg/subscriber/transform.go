@@ -0,0 +1,363 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"encoding/base64"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+	klog "k8s.io/klog/v2"
+
+	claimcheck "github.com/dvonthenen/rabbitmq-manager/pkg/claimcheck"
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	compression "github.com/dvonthenen/rabbitmq-manager/pkg/compression"
+	encryption "github.com/dvonthenen/rabbitmq-manager/pkg/encryption"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	signing "github.com/dvonthenen/rabbitmq-manager/pkg/signing"
+)
+
+/*
+	Reverses the publisher payload transformations before the handler runs
+*/
+func (s *Subscriber) unwrap(delivery *interfaces.Delivery) error {
+	err := s.verify(delivery)
+	if err != nil {
+		return err
+	}
+	if _, found := delivery.Headers[common.HeaderPublishSequence]; found {
+		delivery.Headers = stripHeaders(delivery.Headers, common.HeaderPublishSequence)
+	}
+
+	err = s.fetchClaim(delivery)
+	if err != nil {
+		return err
+	}
+
+	err = s.decrypt(delivery)
+	if err != nil {
+		return err
+	}
+
+	err = s.decompress(delivery)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+/*
+	Returns a copy of the headers without the ones a transformation added, so the
+	handler only sees the headers the publishing application set
+*/
+func stripHeaders(headers amqp.Table, names ...string) amqp.Table {
+	stripped := make(amqp.Table, len(headers))
+	for key, value := range headers {
+		stripped[key] = value
+	}
+	for _, name := range names {
+		delete(stripped, name)
+	}
+	return stripped
+}
+
+/*
+	Settles a delivery the handler could never make sense of. It is dead lettered
+	when the queue has a dead letter exchange and dropped otherwise.
+*/
+func (s *Subscriber) discardUnreadable(d *amqp.Delivery, delivery *interfaces.Delivery, cause error) {
+	if s.options.UnreadableHandler != nil {
+		(*s.options.UnreadableHandler).ProcessUnreadable(delivery, cause)
+	}
+
+	err := s.complete(d, interfaces.AckOutcomeNackDiscard)
+	if err != nil {
+		klog.V(1).Infof("complete() failed. Err: %v\n", err)
+	}
+}
+
+/*
+	Decrypts bodies sealed by an encrypting publisher. Without a KeyProvider the
+	message is passed through as is unless RequireEncryption is set.
+*/
+func (s *Subscriber) decrypt(delivery *interfaces.Delivery) error {
+	if s.options.KeyProvider == nil {
+		return nil
+	}
+
+	algorithm, found := delivery.Headers[encryption.HeaderAlgorithm]
+	if !found {
+		if s.options.RequireEncryption {
+			atomic.AddUint64(&s.stats.decryptionFailures, 1)
+			return &encryption.DecryptionError{
+				MessageId: delivery.MessageId,
+				Err:       encryption.ErrNotEncrypted,
+			}
+		}
+		return nil
+	}
+
+	keyId, _ := delivery.Headers[encryption.HeaderKeyId].(string)
+	body, err := s.openEnvelope(delivery, algorithm, keyId)
+	if err != nil {
+		atomic.AddUint64(&s.stats.decryptionFailures, 1)
+		return &encryption.DecryptionError{
+			KeyId:     keyId,
+			MessageId: delivery.MessageId,
+			Err:       err,
+		}
+	}
+
+	delivery.Headers = stripHeaders(delivery.Headers, encryption.HeaderAlgorithm, encryption.HeaderKeyId, encryption.HeaderDataKey)
+	delivery.Body = body
+
+	atomic.AddUint64(&s.stats.decrypted, 1)
+
+	return nil
+}
+
+func (s *Subscriber) openEnvelope(delivery *interfaces.Delivery, algorithm interface{}, keyId string) ([]byte, error) {
+	if algorithm != encryption.AlgorithmAES256GCM {
+		return nil, encryption.ErrUnknownAlgorithm
+	}
+
+	encoded, ok := delivery.Headers[encryption.HeaderDataKey].(string)
+	if !ok || keyId == "" {
+		return nil, encryption.ErrMalformedEnvelope
+	}
+	wrappedKey, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, encryption.ErrMalformedEnvelope
+	}
+
+	return encryption.Open(*s.options.KeyProvider, keyId, delivery.ContentEncoding, wrappedKey, delivery.Body)
+}
+
+/*
+	Decompresses bodies with a known content-encoding. Other encodings are passed
+	through for the handler to deal with.
+*/
+func (s *Subscriber) decompress(delivery *interfaces.Delivery) error {
+	if !compression.IsSupported(delivery.ContentEncoding) {
+		return nil
+	}
+
+	start := time.Now()
+	data, err := compression.Decompress(delivery.ContentEncoding, delivery.Body, s.options.MaxDecompressedSize)
+	atomic.AddInt64(&s.stats.decompressionTime, int64(time.Since(start)))
+	if err != nil {
+		atomic.AddUint64(&s.stats.decompressionFailures, 1)
+		return err
+	}
+
+	atomic.AddUint64(&s.stats.decompressed, 1)
+	atomic.AddUint64(&s.stats.compressedBytes, uint64(len(delivery.Body)))
+	atomic.AddUint64(&s.stats.decompressedBytes, uint64(len(data)))
+
+	delivery.Body = data
+	delivery.ContentEncoding = ""
+
+	return nil
+}
+
+/*
+	Checks the signature before anything else touches the message. With a
+	Verifier every message must be signed by a known key and cover the
+	RequiredSignedHeaders. Other headers, such as the ones the broker adds, are
+	passed on unauthenticated.
+
+	With a replay window the message must carry a timestamp within the window of
+	now and an id not seen within it. A redelivery by the broker is not a replay,
+	but it keeps its original timestamp so the window must cover the longest
+	redelivery.
+*/
+func (s *Subscriber) verify(delivery *interfaces.Delivery) error {
+	if s.options.Verifier == nil {
+		return nil
+	}
+
+	keyId, _ := delivery.Headers[signing.HeaderKeyId].(string)
+	err := s.checkSignature(delivery, keyId)
+	if err == nil && s.replays != nil {
+		err = s.checkReplay(delivery)
+	}
+	if err != nil {
+		atomic.AddUint64(&s.stats.verificationFailures, 1)
+		return &signing.VerificationError{
+			KeyId:     keyId,
+			MessageId: delivery.MessageId,
+			Err:       err,
+		}
+	}
+
+	delivery.Headers = stripHeaders(delivery.Headers, signing.HeaderSignature, signing.HeaderAlgorithm, signing.HeaderKeyId, signing.HeaderSignedHeaders)
+
+	atomic.AddUint64(&s.stats.verified, 1)
+
+	return nil
+}
+
+func (s *Subscriber) checkSignature(delivery *interfaces.Delivery, keyId string) error {
+	encoded, found := delivery.Headers[signing.HeaderSignature]
+	if !found {
+		return signing.ErrMissingSignature
+	}
+
+	encodedSignature, ok := encoded.(string)
+	if !ok {
+		return signing.ErrMalformedSignature
+	}
+	algorithm, ok := delivery.Headers[signing.HeaderAlgorithm].(string)
+	if !ok || keyId == "" {
+		return signing.ErrMalformedSignature
+	}
+	signedHeaders, ok := delivery.Headers[signing.HeaderSignedHeaders].(string)
+	if !ok {
+		return signing.ErrMalformedSignature
+	}
+	signature, err := base64.StdEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return signing.ErrMalformedSignature
+	}
+
+	var names []string
+	if signedHeaders != "" {
+		names = strings.Split(signedHeaders, ",")
+	}
+	for _, required := range s.options.RequiredSignedHeaders {
+		if !containsString(names, required) {
+			return signing.ErrUnsignedHeader
+		}
+	}
+	// an unsigned claim could point at any stored body
+	if _, found := delivery.Headers[claimcheck.HeaderClaimCheck]; found {
+		for _, required := range claimcheck.Headers {
+			if !containsString(names, required) {
+				return signing.ErrUnsignedHeader
+			}
+		}
+	}
+
+	data, err := signing.Canonical(&signing.Content{
+		MessageId:       delivery.MessageId,
+		Timestamp:       delivery.Timestamp,
+		ContentType:     delivery.ContentType,
+		ContentEncoding: delivery.ContentEncoding,
+		Headers:         delivery.Headers,
+		SignedHeaders:   names,
+		Body:            delivery.Body,
+	})
+	if err != nil {
+		return err
+	}
+
+	return (*s.options.Verifier).Verify(algorithm, keyId, data, signature)
+}
+
+func (s *Subscriber) checkReplay(delivery *interfaces.Delivery) error {
+	if delivery.Timestamp.IsZero() {
+		return signing.ErrMissingTimestamp
+	}
+
+	age := time.Since(delivery.Timestamp)
+	if age > s.options.ReplayWindow || age < -s.options.ReplayWindow {
+		return signing.ErrOutsideReplayWindow
+	}
+
+	if delivery.Redelivered {
+		return nil
+	}
+	return s.replays.Check(delivery.MessageId, delivery.Timestamp)
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+/*
+	Replaces the body of a claim checked message with the one in the BlobStore,
+	after checking it has the claimed size and digest. Without a BlobStore the
+	message is passed through with its reference.
+*/
+func (s *Subscriber) fetchClaim(delivery *interfaces.Delivery) error {
+	if s.options.BlobStore == nil {
+		return nil
+	}
+
+	value, found := delivery.Headers[claimcheck.HeaderClaimCheck]
+	if !found {
+		return nil
+	}
+
+	key, _ := value.(string)
+	body, err := s.openClaim(delivery, key)
+	if err != nil {
+		atomic.AddUint64(&s.stats.claimCheckFailures, 1)
+		return &claimcheck.ClaimError{
+			Key:       key,
+			MessageId: delivery.MessageId,
+			Err:       err,
+		}
+	}
+
+	delivery.Headers = stripHeaders(delivery.Headers, claimcheck.Headers...)
+	delivery.Body = body
+
+	atomic.AddUint64(&s.stats.claimChecked, 1)
+
+	return nil
+}
+
+func (s *Subscriber) openClaim(delivery *interfaces.Delivery, key string) ([]byte, error) {
+	size, ok := delivery.Headers[claimcheck.HeaderClaimCheckSize].(int64)
+	if !ok || key == "" {
+		return nil, claimcheck.ErrMalformedClaim
+	}
+	digest, ok := delivery.Headers[claimcheck.HeaderClaimCheckDigest].(string)
+	if !ok {
+		return nil, claimcheck.ErrMalformedClaim
+	}
+
+	body, err := (*s.options.BlobStore).Get(key)
+	if err != nil {
+		return nil, err
+	}
+
+	err = claimcheck.Check(body, size, digest)
+	if err != nil {
+		return nil, err
+	}
+
+	return body, nil
+}
+
+/*
+	With ClaimCheckDelete, deletes the stored body of an acked message. Only
+	safe when no other queue receives the message, otherwise the first ack
+	removes the body the others still need. Dead lettered and requeued messages
+	keep theirs, as do streams since other consumers can still read them.
+*/
+func (s *Subscriber) releaseClaim(d *amqp.Delivery) {
+	if s.options.BlobStore == nil || !s.options.ClaimCheckDelete || s.stream {
+		return
+	}
+
+	key, found := d.Headers[claimcheck.HeaderClaimCheck].(string)
+	if !found {
+		return
+	}
+
+	err := (*s.options.BlobStore).Delete(key)
+	if err != nil {
+		klog.V(1).Infof("Delete %s failed. Err: %v\n", key, err)
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package subscriber
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+
+	amqp "github.com/rabbitmq/amqp091-go"
+
+	common "github.com/dvonthenen/rabbitmq-manager/pkg/common"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	signing "github.com/dvonthenen/rabbitmq-manager/pkg/signing"
+)
+
+func TestStripHeaders(t *testing.T) {
+	headers := amqp.Table{"app": "value", "x-a": 1, "x-b": 2}
+
+	stripped := stripHeaders(headers, "x-a", "x-b", "missing")
+	if len(stripped) != 1 || stripped["app"] != "value" {
+		t.Fatalf("stripHeaders() = %v", stripped)
+	}
+	if len(headers) != 3 {
+		t.Fatalf("stripHeaders changed the delivery headers: %v", headers)
+	}
+	if len(stripHeaders(nil)) != 0 {
+		t.Fatalf("stripHeaders(nil) not empty")
+	}
+}
+
+func signedDelivery(t *testing.T, key []byte, headers amqp.Table, names []string) *interfaces.Delivery {
+	t.Helper()
+
+	signer, err := signing.NewHMACSigner("k1", key)
+	if err != nil {
+		t.Fatalf("NewHMACSigner failed. Err: %v", err)
+	}
+
+	delivery := &interfaces.Delivery{MessageId: "id", Headers: headers, Body: []byte("body")}
+	data, err := signing.Canonical(&signing.Content{
+		MessageId:     delivery.MessageId,
+		Headers:       delivery.Headers,
+		SignedHeaders: names,
+		Body:          delivery.Body,
+	})
+	if err != nil {
+		t.Fatalf("Canonical failed. Err: %v", err)
+	}
+	signature, err := signer.Sign(data)
+	if err != nil {
+		t.Fatalf("Sign failed. Err: %v", err)
+	}
+
+	headers[signing.HeaderSignature] = base64.StdEncoding.EncodeToString(signature)
+	headers[signing.HeaderAlgorithm] = signer.Algorithm()
+	headers[signing.HeaderKeyId] = signer.KeyId()
+	headers[signing.HeaderSignedHeaders] = common.HeaderPublishSequence
+
+	return delivery
+}
+
+func TestUnwrapPublishSequence(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	keys := signing.NewKeyVerifier()
+	err := keys.AddHMACKey("k1", key)
+	if err != nil {
+		t.Fatalf("AddHMACKey failed. Err: %v", err)
+	}
+	var verifier interfaces.Verifier = keys
+
+	tests := []struct {
+		name     string
+		verifier *interfaces.Verifier
+		tamper   bool
+		err      error
+	}{
+		{"verified", &verifier, false, nil},
+		{"tampered", &verifier, true, signing.ErrInvalidSignature},
+		{"without a verifier", nil, false, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &Subscriber{
+				options: SubscriberOptions{
+					SubscriberOptions: &interfaces.SubscriberOptions{Verifier: tt.verifier},
+				},
+			}
+
+			headers := amqp.Table{common.HeaderPublishSequence: int64(7), "app": "value"}
+			delivery := signedDelivery(t, key, headers, []string{common.HeaderPublishSequence})
+			if tt.tamper {
+				delivery.Headers[common.HeaderPublishSequence] = int64(8)
+			}
+
+			err := s.unwrap(delivery)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("unwrap() err = %v, want %v", err, tt.err)
+			}
+			if err != nil {
+				return
+			}
+			if _, found := delivery.Headers[common.HeaderPublishSequence]; found {
+				t.Fatalf("sequence header reached the handler: %v", delivery.Headers)
+			}
+			if delivery.Headers["app"] != "value" {
+				t.Fatalf("application header lost: %v", delivery.Headers)
+			}
+		})
+	}
+}
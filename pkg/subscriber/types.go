@@ -4,9 +4,16 @@
 package subscriber
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	amqp "github.com/rabbitmq/amqp091-go"
 
+	circuitbreaker "github.com/dvonthenen/rabbitmq-manager/pkg/circuitbreaker"
 	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+	ratelimit "github.com/dvonthenen/rabbitmq-manager/pkg/ratelimit"
+	signing "github.com/dvonthenen/rabbitmq-manager/pkg/signing"
 )
 
 type SubscriberOptions struct {
@@ -15,11 +22,66 @@ type SubscriberOptions struct {
 	Channel *amqp.Channel
 }
 
+type binding struct {
+	key  string
+	args amqp.Table
+}
+
 type Subscriber struct {
-	options  SubscriberOptions
-	channel  *amqp.Channel
-	queue    *amqp.Queue
-	stopChan chan struct{}
-	handler  *interfaces.RabbitMessageHandler
-	running  bool
+	options     SubscriberOptions
+	channel     *amqp.Channel
+	queue       *amqp.Queue
+	queueName   string
+	queueArgs   amqp.Table
+	bindings    []binding
+	consumerTag string
+	doneChan    chan struct{}
+	handler     interfaces.RabbitAckHandler
+	running     bool
+	consumeMu   sync.Mutex
+	stats       stats
+
+	// batch
+	batchHandler interfaces.RabbitBatchHandler
+	batchSize    int
+	batchTimeout time.Duration
+
+	// rate limiting
+	limiter      *ratelimit.Throttle
+	stopThrottle context.CancelFunc
+	throttleCtx  context.Context
+
+	// circuit breaker
+	breaker *circuitbreaker.Breaker
+	paused  bool
+
+	// signing
+	replays *signing.ReplayCache
+
+	// qos
+	prefetchCount int
+	prefetchSize  int
+	qosMu         sync.Mutex
+
+	// deferred acknowledgement
+	outstanding map[*acknowledger]struct{}
+	ackMu       sync.Mutex
+
+	// stream
+	stream          bool
+	offsetName      string
+	offsetStore     *interfaces.OffsetStore
+	lastOffset      int64
+	hasOffset       bool
+	maxCompleted    int64
+	hasCompleted    bool
+	inflightOffsets map[int64]struct{}
+	offsetMu        sync.Mutex
+
+	// offset commits
+	offsetCommitCount    int
+	offsetCommitInterval time.Duration
+	uncommitted          int
+	commitTimer          *time.Timer
+	commitMu             sync.Mutex
 }
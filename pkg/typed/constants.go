@@ -0,0 +1,16 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package typed
+
+import (
+	"errors"
+)
+
+var (
+	// ErrInvalidInput required input was not found
+	ErrInvalidInput = errors.New("required input was not found")
+
+	// ErrHandlerConflict the typed subscriber provides the handler so no other handler can be set
+	ErrHandlerConflict = errors.New("the typed subscriber provides the handler so no other handler can be set")
+)
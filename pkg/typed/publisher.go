@@ -0,0 +1,91 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package typed
+
+import (
+	klog "k8s.io/klog/v2"
+
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+func NewTypedPublisher[T any](publisher *interfaces.Publisher, codec interfaces.Codec) (*TypedPublisher[T], error) {
+	if publisher == nil || codec == nil {
+		klog.V(1).Infof("NewTypedPublisher requires a publisher and a codec\n")
+		return nil, ErrInvalidInput
+	}
+
+	p := &TypedPublisher[T]{
+		publisher: publisher,
+		codec:     codec,
+	}
+	return p, nil
+}
+
+/*
+	Creates the underlying Publisher through the Manager
+*/
+func CreateTypedPublisher[T any](manager *interfaces.Manager, options interfaces.PublisherOptions, codec interfaces.Codec) (*TypedPublisher[T], error) {
+	if manager == nil || codec == nil {
+		klog.V(1).Infof("CreateTypedPublisher requires a manager and a codec\n")
+		return nil, ErrInvalidInput
+	}
+
+	publisher, err := (*manager).CreatePublisher(options)
+	if err != nil {
+		klog.V(1).Infof("CreatePublisher failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	return NewTypedPublisher[T](publisher, codec)
+}
+
+func (p *TypedPublisher[T]) Publisher() *interfaces.Publisher {
+	return p.publisher
+}
+
+func (p *TypedPublisher[T]) Send(value T) error {
+	return p.SendWithProperties(interfaces.Message{}, value)
+}
+
+func (p *TypedPublisher[T]) SendWithKey(key string, value T) error {
+	return p.SendWithProperties(interfaces.Message{
+		RoutingKey: key,
+	}, value)
+}
+
+/*
+	Encodes the value into the message body. The content type is the one of the
+	codec unless the message already has one.
+*/
+func (p *TypedPublisher[T]) SendWithProperties(msg interfaces.Message, value T) error {
+	err := p.encode(&msg, value)
+	if err != nil {
+		return err
+	}
+	return (*p.publisher).SendMessageWithProperties(msg)
+}
+
+func (p *TypedPublisher[T]) SendAsync(value T) (interfaces.Confirmation, error) {
+	msg := interfaces.Message{}
+
+	err := p.encode(&msg, value)
+	if err != nil {
+		return nil, err
+	}
+	return (*p.publisher).SendMessageWithPropertiesAsync(msg)
+}
+
+func (p *TypedPublisher[T]) encode(msg *interfaces.Message, value T) error {
+	body, err := p.codec.Marshal(value)
+	if err != nil {
+		klog.V(1).Infof("Marshal failed. Err: %v\n", err)
+		return err
+	}
+
+	msg.Body = body
+	if msg.ContentType == "" {
+		msg.ContentType = p.codec.ContentType()
+	}
+	return nil
+}
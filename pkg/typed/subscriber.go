@@ -0,0 +1,113 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package typed
+
+import (
+	"sync/atomic"
+
+	klog "k8s.io/klog/v2"
+
+	codec "github.com/dvonthenen/rabbitmq-manager/pkg/codec"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Creates a typed subscriber that is not yet attached to a Subscriber. Use it as
+	the AckHandler of a Subscriber, or use CreateTypedSubscriber.
+*/
+func NewTypedSubscriber[T any](options TypedSubscriberOptions[T]) (*TypedSubscriber[T], error) {
+	if options.Handler == nil {
+		klog.V(1).Infof("NewTypedSubscriber requires a handler\n")
+		return nil, ErrInvalidInput
+	}
+
+	s := &TypedSubscriber[T]{
+		options: options,
+	}
+	return s, nil
+}
+
+/*
+	Creates the underlying Subscriber through the Manager with the typed
+	subscriber as its handler
+*/
+func CreateTypedSubscriber[T any](manager *interfaces.Manager, options interfaces.SubscriberOptions, typedOptions TypedSubscriberOptions[T]) (*TypedSubscriber[T], error) {
+	if manager == nil {
+		klog.V(1).Infof("CreateTypedSubscriber requires a manager\n")
+		return nil, ErrInvalidInput
+	}
+	if options.Handler != nil || options.DeliveryHandler != nil || options.AckHandler != nil || options.BatchHandler != nil {
+		klog.V(1).Infof("Subscriber %s already has a handler\n", options.Name)
+		return nil, ErrHandlerConflict
+	}
+
+	s, err := NewTypedSubscriber(typedOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	var ackHandler interfaces.RabbitAckHandler
+	ackHandler = s
+	options.AckHandler = &ackHandler
+
+	s.subscriber, err = (*manager).CreateSubscriber(options)
+	if err != nil {
+		klog.V(1).Infof("CreateSubscriber failed. Err: %v\n", err)
+		return nil, err
+	}
+
+	return s, nil
+}
+
+func (s *TypedSubscriber[T]) Subscriber() *interfaces.Subscriber {
+	return s.subscriber
+}
+
+/*
+	Number of messages that could not be decoded
+*/
+func (s *TypedSubscriber[T]) GetDecodeFailures() uint64 {
+	return atomic.LoadUint64(&s.decodeFailures)
+}
+
+/*
+	Decodes the delivery with the configured codec. Without one the codec is
+	picked from the registry by the content type of the delivery.
+*/
+func (s *TypedSubscriber[T]) HandleDelivery(delivery *interfaces.Delivery) (interfaces.AckOutcome, error) {
+	decoder := s.options.Codec
+	if decoder == nil {
+		var err error
+		decoder, err = codec.ForContentType(delivery.ContentType)
+		if err != nil {
+			klog.V(1).Infof("No codec for %s. Err: %v\n", delivery.ContentType, err)
+			return s.decodeFailed(delivery), nil
+		}
+	}
+
+	var value T
+	err := decoder.Unmarshal(delivery.Body, &value)
+	if err != nil {
+		klog.V(1).Infof("Unmarshal %s failed. Err: %v\n", delivery.ContentType, err)
+		return s.decodeFailed(delivery), nil
+	}
+
+	return interfaces.AckOutcomeDefault, s.options.Handler.ProcessValue(value, delivery)
+}
+
+/*
+	Settles an undecodable message without involving the handler. Dead lettering
+	needs a dead letter exchange on the queue, otherwise the broker drops it too.
+*/
+func (s *TypedSubscriber[T]) decodeFailed(delivery *interfaces.Delivery) interfaces.AckOutcome {
+	atomic.AddUint64(&s.decodeFailures, 1)
+
+	if s.options.DecodeFailure == interfaces.DecodeFailurePolicyDrop {
+		klog.V(3).Infof("Dropping undecodable message %s\n", delivery.MessageId)
+		return interfaces.AckOutcomeAck
+	}
+
+	klog.V(3).Infof("Dead lettering undecodable message %s\n", delivery.MessageId)
+	return interfaces.AckOutcomeNackDiscard
+}
@@ -0,0 +1,136 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package typed
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	codec "github.com/dvonthenen/rabbitmq-manager/pkg/codec"
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+type order struct {
+	Id    string `json:"id"`
+	Total int    `json:"total"`
+}
+
+type orderHandler struct {
+	orders []order
+}
+
+func (h *orderHandler) ProcessValue(value order, delivery *interfaces.Delivery) error {
+	h.orders = append(h.orders, value)
+	return nil
+}
+
+/*
+	Codec only known to the subscriber, never registered
+*/
+type upperJSONCodec struct{}
+
+func (c upperJSONCodec) ContentType() string {
+	return "application/x-upper-json"
+}
+
+func (c upperJSONCodec) Marshal(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	return []byte(strings.ToUpper(string(data))), err
+}
+
+func (c upperJSONCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal([]byte(strings.ToLower(string(data))), v)
+}
+
+func TestHandleDeliveryCodecSelection(t *testing.T) {
+	body, _ := json.Marshal(order{Id: "a", Total: 3})
+
+	tests := []struct {
+		name        string
+		codec       interfaces.Codec
+		contentType string
+		body        []byte
+		outcome     interfaces.AckOutcome
+		decoded     bool
+	}{
+		{"configured codec with its content type", codec.NewJSONCodec(), codec.ContentTypeJSON, body, interfaces.AckOutcomeDefault, true},
+		{"configured codec with the publisher default content type", codec.NewJSONCodec(), "text/plain", body, interfaces.AckOutcomeDefault, true},
+		{"configured codec without content type", codec.NewJSONCodec(), "", body, interfaces.AckOutcomeDefault, true},
+		{"unregistered configured codec", upperJSONCodec{}, "application/x-upper-json", []byte(strings.ToUpper(string(body))), interfaces.AckOutcomeDefault, true},
+		{"registry by content type", nil, codec.ContentTypeJSON + "; charset=utf-8", body, interfaces.AckOutcomeDefault, true},
+		{"registry with unknown content type", nil, "text/plain", body, interfaces.AckOutcomeNackDiscard, false},
+		{"registry without content type", nil, "", body, interfaces.AckOutcomeNackDiscard, false},
+		{"undecodable body", codec.NewJSONCodec(), codec.ContentTypeJSON, []byte("{"), interfaces.AckOutcomeNackDiscard, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &orderHandler{}
+			s, err := NewTypedSubscriber[order](TypedSubscriberOptions[order]{
+				Codec:   tt.codec,
+				Handler: handler,
+			})
+			if err != nil {
+				t.Fatalf("NewTypedSubscriber failed. Err: %v", err)
+			}
+
+			outcome, err := s.HandleDelivery(&interfaces.Delivery{
+				ContentType: tt.contentType,
+				Body:        tt.body,
+			})
+			if err != nil {
+				t.Fatalf("HandleDelivery failed. Err: %v", err)
+			}
+			if outcome != tt.outcome {
+				t.Fatalf("outcome = %v, want %v", outcome, tt.outcome)
+			}
+			if tt.decoded != (len(handler.orders) == 1) {
+				t.Fatalf("handler received %v", handler.orders)
+			}
+			if tt.decoded && handler.orders[0].Id != "a" {
+				t.Fatalf("decoded %+v", handler.orders[0])
+			}
+		})
+	}
+}
+
+func TestDecodeFailurePolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  interfaces.DecodeFailurePolicy
+		outcome interfaces.AckOutcome
+	}{
+		{"dead letter", interfaces.DecodeFailurePolicyDeadLetter, interfaces.AckOutcomeNackDiscard},
+		{"drop", interfaces.DecodeFailurePolicyDrop, interfaces.AckOutcomeAck},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := NewTypedSubscriber[order](TypedSubscriberOptions[order]{
+				Codec:         codec.NewJSONCodec(),
+				Handler:       &orderHandler{},
+				DecodeFailure: tt.policy,
+			})
+			if err != nil {
+				t.Fatalf("NewTypedSubscriber failed. Err: %v", err)
+			}
+
+			outcome, _ := s.HandleDelivery(&interfaces.Delivery{Body: []byte("not json")})
+			if outcome != tt.outcome {
+				t.Fatalf("outcome = %v, want %v", outcome, tt.outcome)
+			}
+			if s.GetDecodeFailures() != 1 {
+				t.Fatalf("GetDecodeFailures() = %d, want 1", s.GetDecodeFailures())
+			}
+		})
+	}
+}
+
+func TestNewTypedSubscriberRequiresHandler(t *testing.T) {
+	_, err := NewTypedSubscriber[order](TypedSubscriberOptions[order]{Codec: codec.NewJSONCodec()})
+	if err != ErrInvalidInput {
+		t.Fatalf("NewTypedSubscriber() err = %v, want %v", err, ErrInvalidInput)
+	}
+}
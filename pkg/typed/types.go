@@ -0,0 +1,43 @@
+// Copyright 2023. All Rights Reserved.
+// SPDX-License-Identifier: MIT
+
+package typed
+
+import (
+	interfaces "github.com/dvonthenen/rabbitmq-manager/pkg/interfaces"
+)
+
+/*
+	Receives decoded values. Use pointer types for Protocol Buffers messages.
+*/
+type TypedHandler[T any] interface {
+	ProcessValue(value T, delivery *interfaces.Delivery) error
+}
+
+/*
+	Publisher which encodes values with a Codec
+*/
+type TypedPublisher[T any] struct {
+	publisher *interfaces.Publisher
+	codec     interfaces.Codec
+}
+
+/*
+	Without a Codec each delivery is decoded with the registered codec for its
+	content type
+*/
+type TypedSubscriberOptions[T any] struct {
+	Codec         interfaces.Codec
+	Handler       TypedHandler[T]
+	DecodeFailure interfaces.DecodeFailurePolicy
+}
+
+/*
+	Subscriber which decodes messages before handing them to a TypedHandler
+*/
+type TypedSubscriber[T any] struct {
+	subscriber *interfaces.Subscriber
+	options    TypedSubscriberOptions[T]
+
+	decodeFailures uint64
+}